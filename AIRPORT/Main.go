@@ -0,0 +1,90 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+/*
+ * runs the airport network model (see models/airport) and prints its
+ * statistics
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jeffallen/go-warp/models/airport"
+	"github.com/jeffallen/go-warp/warp"
+	"os"
+	"runtime"
+)
+
+const usage = "airport [flags]"
+
+func main() {
+	p := airport.DefaultParams()
+	lps := flag.Int("lps", 0, "number of LPs, 0 means one per CPU")
+	seq := flag.Bool("seq", false, "run on the sequential reference kernel")
+	seed := flag.Int64("seed", 1, "seed of the random streams of the airports")
+	window := flag.Int("window", 4*3600, "limited optimism: the LPs do not execute the events after GVT + window, 0 means no limit")
+	partition := flag.String("partition", "graph", "placement of the airports: graph (by the routes), block, roundrobin or hash")
+	flag.IntVar(&p.Airports, "airports", p.Airports, "number of airports")
+	flag.StringVar(&p.Shape, "shape", p.Shape, "graph of the routes: ring, grid, star, random or complete")
+	flag.IntVar(&p.Degree, "degree", p.Degree, "mean routes per airport of the random graph")
+	flag.Int64Var(&p.Seed, "graph-seed", p.Seed, "seed of the random graph and of the positions of the airports")
+	flag.IntVar(&p.Planes, "planes", p.Planes, "aircraft of every airport at the start")
+	flag.IntVar(&p.Runways, "runways", p.Runways, "runways of every airport")
+	flag.Float64Var(&p.Land, "land", p.Land, "mean runway time of a landing, seconds")
+	flag.Float64Var(&p.Takeoff, "takeoff", p.Takeoff, "mean runway time of a takeoff, seconds")
+	flag.Float64Var(&p.Ground, "ground", p.Ground, "mean time on the ground, seconds")
+	endtime := flag.Int("endtime", int(p.EndTime), "simulated time, seconds")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "%s\n\n", usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	p.EndTime = warp.Time(*endtime)
+
+	cfg := warp.Config{LPs: *lps, Sequential: *seq, Seed: *seed, Window: warp.Time(*window)}
+	if cfg.LPs == 0 {
+		cfg.LPs = runtime.NumCPU() // as warp.Run, the partitions need it
+	}
+	switch *partition {
+	case "graph": // the default of airport.Run
+	case "block":
+		cfg.Partition = warp.BlockPartition{Entities: p.Airports, LPs: cfg.LPs}
+	case "roundrobin":
+		cfg.Partition = warp.RoundRobinPartition{LPs: cfg.LPs}
+	case "hash":
+		cfg.Partition = warp.HashPartition{LPs: cfg.LPs}
+	default:
+		fmt.Println("GO-WARP, ERROR: unknown partition", *partition)
+		os.Exit(1)
+	}
+
+	warp.Quiet = true
+	res, err := airport.Run(cfg, p)
+	if err != nil {
+		fmt.Println("GO-WARP, ERROR:", err)
+		os.Exit(1)
+	}
+
+	s := airport.Totals()
+	land, takeoff := s.MeanWaits()
+	fmt.Printf("AIRPORT: %d airports (%s), %d aircraft, %d LPs, end time %d\n", p.Airports, p.Shape,
+		p.Airports*p.Planes, res.Stats.LPs, p.EndTime)
+	fmt.Printf("arrivals %d, landings %d, takeoffs %d, longest landing queue %d\n", s.Arrivals, s.Landings, s.Takeoffs, s.MaxQueue)
+	fmt.Printf("mean wait to land %.1f s, to take off %.1f s\n", land, takeoff)
+	fmt.Printf("%d committed events, %d rollbacks, %v\n", res.Stats.Committed, res.Stats.Rollbacks, res.Stats.WallClock)
+}
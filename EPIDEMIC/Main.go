@@ -0,0 +1,86 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+/*
+ * runs the epidemic model (see models/epidemic) and prints the committed
+ * counts of every day, as soon as the GVT passes its end
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jeffallen/go-warp/models/epidemic"
+	"github.com/jeffallen/go-warp/warp"
+	"os"
+	"runtime"
+)
+
+const usage = "epidemic [flags]"
+
+func main() {
+	p := epidemic.DefaultParams()
+	lps := flag.Int("lps", 0, "number of LPs, 0 means one per CPU")
+	seq := flag.Bool("seq", false, "run on the sequential reference kernel")
+	seed := flag.Int64("seed", 1, "seed of the random streams of the individuals")
+	window := flag.Int("window", 2*epidemic.DAY, "limited optimism: the LPs do not execute the events after GVT + window (minutes), 0 means no limit")
+	partition := flag.String("partition", "block", "placement of the individuals: block, roundrobin or hash")
+	flag.IntVar(&p.Individuals, "individuals", p.Individuals, "number of individuals")
+	flag.IntVar(&p.Degree, "degree", p.Degree, "contacts of an individual, even")
+	flag.Float64Var(&p.Rewire, "rewire", p.Rewire, "probability that a contact is rewired to a random individual")
+	flag.Int64Var(&p.Seed, "graph-seed", p.Seed, "seed of the contact network and of the initial infections")
+	flag.Float64Var(&p.Beta, "beta", p.Beta, "transmission rate of a contact, per day")
+	flag.Float64Var(&p.Latent, "latent", p.Latent, "mean latent period, days; 0 is the SIR model")
+	flag.Float64Var(&p.Infectious, "infectious", p.Infectious, "mean infectious period, days")
+	flag.IntVar(&p.Initial, "initial", p.Initial, "individuals infected at the start")
+	flag.IntVar(&p.Days, "days", p.Days, "simulated days")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "%s\n\n", usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := warp.Config{LPs: *lps, Sequential: *seq, Seed: *seed, Window: warp.Time(*window)}
+	if cfg.LPs == 0 {
+		cfg.LPs = runtime.NumCPU() // as warp.Run, the partitions need it
+	}
+	switch *partition {
+	case "block":
+	case "roundrobin":
+		cfg.Partition = warp.RoundRobinPartition{LPs: cfg.LPs}
+	case "hash":
+		cfg.Partition = warp.HashPartition{LPs: cfg.LPs}
+	default:
+		fmt.Println("GO-WARP, ERROR: unknown partition", *partition)
+		os.Exit(1)
+	}
+
+	warp.Quiet = true
+	fmt.Printf("# EPIDEMIC: %d individuals, %d LPs, %d days\n", p.Individuals, cfg.LPs, p.Days)
+	fmt.Println("day,susceptible,exposed,infectious,recovered,new")
+	epidemic.OnDay = func(d epidemic.Day) {
+		fmt.Printf("%d,%d,%d,%d,%d,%d\n", d.Day, d.Susceptible, d.Exposed, d.Infectious, d.Recovered, d.New)
+	}
+	res, err := epidemic.Run(cfg, p)
+	if err != nil {
+		fmt.Println("GO-WARP, ERROR:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("# %d contacts\n", epidemic.CurrentContacts().Edges())
+	fmt.Printf("# %d committed events, %d rollbacks, %v\n", res.Stats.Committed, res.Stats.Rollbacks, res.Stats.WallClock)
+}
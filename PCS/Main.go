@@ -0,0 +1,86 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+/*
+ * runs the PCS cellular network model (see models/pcs) and prints its
+ * statistics together with the steady state expected by the Erlang B
+ * fixed point
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jeffallen/go-warp/models/pcs"
+	"github.com/jeffallen/go-warp/warp"
+	"os"
+	"runtime"
+)
+
+const usage = "pcs [flags]"
+
+func main() {
+	p := pcs.DefaultParams()
+	lps := flag.Int("lps", 0, "number of LPs, 0 means one per CPU")
+	seq := flag.Bool("seq", false, "run on the sequential reference kernel")
+	seed := flag.Int64("seed", 1, "seed of the random streams of the cells")
+	window := flag.Int("window", 500, "limited optimism: the LPs do not execute the events after GVT + window, 0 means no limit")
+	partition := flag.String("partition", "block", "placement of the cells: block, roundrobin or hash")
+	flag.IntVar(&p.Rows, "rows", p.Rows, "rows of the torus of cells")
+	flag.IntVar(&p.Cols, "cols", p.Cols, "columns of the torus of cells")
+	flag.IntVar(&p.Channels, "channels", p.Channels, "radio channels of every cell")
+	flag.Float64Var(&p.ArrivalMean, "arrival", p.ArrivalMean, "mean time between two new calls in a cell")
+	flag.Float64Var(&p.CallMean, "call", p.CallMean, "mean duration of a call")
+	flag.Float64Var(&p.MoveMean, "move", p.MoveMean, "mean time spent by a portable in a cell")
+	endtime := flag.Int("endtime", int(p.EndTime), "simulated time")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "%s\n\n", usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	p.EndTime = warp.Time(*endtime)
+
+	cfg := warp.Config{LPs: *lps, Sequential: *seq, Seed: *seed, Window: warp.Time(*window)}
+	if cfg.LPs == 0 {
+		cfg.LPs = runtime.NumCPU() // as warp.Run, the partitions need it
+	}
+	switch *partition {
+	case "block":
+	case "roundrobin":
+		cfg.Partition = warp.RoundRobinPartition{LPs: cfg.LPs}
+	case "hash":
+		cfg.Partition = warp.HashPartition{LPs: cfg.LPs}
+	default:
+		fmt.Println("GO-WARP, ERROR: unknown partition", *partition)
+		os.Exit(1)
+	}
+
+	warp.Quiet = true
+	res, err := pcs.Run(cfg, p)
+	if err != nil {
+		fmt.Println("GO-WARP, ERROR:", err)
+		os.Exit(1)
+	}
+
+	s, e := pcs.Totals(), pcs.Expected(p)
+	fmt.Printf("PCS: %dx%d cells, %d channels, %d LPs, end time %d\n", p.Rows, p.Cols, p.Channels, res.Stats.LPs, p.EndTime)
+	fmt.Printf("calls %d, blocked %d, completed %d, handoffs %d, dropped %d\n",
+		s.Attempts, s.Blocked, s.Completed, s.Handoffs, s.HandoffsBlocked)
+	fmt.Printf("blocking %.4f (expected %.4f), dropping %.4f, offered load %.2f Erlangs\n",
+		s.Blocking(), e.Blocking, s.Dropping(), e.Load)
+	fmt.Printf("%d committed events, %d rollbacks, %v\n", res.Stats.Committed, res.Stats.Rollbacks, res.Stats.WallClock)
+}
@@ -0,0 +1,106 @@
+/*
+	Go-based implementation of the PHOLD synthetic benchmark
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/jeffallen/go-warp/models/phold"
+	"github.com/jeffallen/go-warp/warp"
+	"os"
+	"time"
+)
+
+var restartTime warp.Time // the time of the checkpoint used to restart
+
+func restartPhold(path string) []*warp.LocalData {
+	c, err := warp.ReadCheckpointFile(path)
+	if err != nil {
+		fmt.Println("GO-WARP, error reading the checkpoint:", err)
+		os.Exit(1)
+	}
+
+	warp.SetCheckpointer(phold.Checkpointer{})
+	warp.Queue = queues[cfg.Kernel.Queue]
+	data, err := warp.Restart(c, warp.EntityManager)
+	if err != nil {
+		fmt.Println("GO-WARP, error restarting from the checkpoint:", err)
+		os.Exit(1)
+	}
+	warp.SetEntities(phold.Entities(), nil) // the partition saved in the checkpoint
+	warp.SetStreams(int64(phold.Current().Entities), phold.Source())
+	warp.Window, warp.GvtThreshold = cfg.Kernel.Window, cfg.Kernel.GvtThreshold
+	lpnum = c.Lpnum
+	endtime = c.EndTime
+	restartTime = c.Gvt
+	fmt.Println("GO-WARP: restarted from", path, "at time", c.Gvt)
+	return data
+}
+
+// the simulation is executed in slices of -every time units, after each
+// slice all the LPs are stopped and the checkpoint is written
+func runCheckpointed(ctx context.Context, data []*warp.LocalData) {
+	warp.SetCheckpointer(phold.Checkpointer{})
+	if data == nil {
+		p := cfg.Model
+		if err := phold.Setup(p); err != nil {
+			fmt.Println("GO-WARP, ERROR:", err)
+			os.Exit(1)
+		}
+		warp.SimSetup(lpnum, endtime, warp.EntityManager)
+		warp.SetEntities(phold.Entities(), partitions[cfg.Kernel.Partition](p.Entities, lpnum))
+		warp.SetStreams(int64(p.Entities), phold.Source())
+		warp.Window, warp.GvtThreshold, warp.Queue = cfg.Kernel.Window, cfg.Kernel.GvtThreshold, queues[cfg.Kernel.Queue]
+		data = make([]*warp.LocalData, lpnum)
+		for i := 0; i < lpnum; i++ {
+			data[i] = warp.SimInitialize(warp.Pid(i))
+			phold.InitLP(data[i])
+		}
+	}
+
+	horizon := restartTime
+	for {
+		if cfg.Checkpoint.Every > 0 {
+			horizon += cfg.Checkpoint.Every
+		} else {
+			horizon = endtime
+		}
+		warp.Resume(horizon)
+
+		if warp.RunLPs(ctx, data).Cancelled || warp.Horizon >= endtime {
+			break
+		}
+		if cfg.Checkpoint.File != "" {
+			c, err := warp.TakeCheckpoint()
+			if err == nil {
+				err = warp.WriteCheckpointFile(c, cfg.Checkpoint.File)
+			}
+			if err != nil {
+				fmt.Println("GO-WARP, error writing the checkpoint:", err)
+				os.Exit(1)
+			}
+			fmt.Println("GO-WARP: checkpoint at time", c.Gvt, "written to", cfg.Checkpoint.File)
+		}
+	}
+
+	elapsedT = time.Since(startT)
+	if err := warp.Failure(); err != nil {
+		fmt.Println("GO-WARP, ERROR:", err)
+		os.Exit(1)
+	}
+
+	for i := range data {
+		terminate(data[i])
+	}
+}
@@ -0,0 +1,449 @@
+/*
+	Go-based implementation of the PHOLD synthetic benchmark
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package main
+
+/*
+ * THE PHOLD CONFIGURATION
+ *
+ * every parameter has a name, its path in the JSON config file,
+ * e.g. model.density. The values are taken, in order of precedence, from
+ * the command line flags, the environment (PHOLD_MODEL_DENSITY), the
+ * config file given with -config and the defaults of defaultConfig
+ */
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jeffallen/go-warp/models/phold"
+	"github.com/jeffallen/go-warp/warp"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	LPs        int              `json:"lps"`        // 0 means one per CPU
+	Sequential bool             `json:"sequential"` // run on the sequential reference kernel
+	Timeout    Duration         `json:"timeout"`    // stop the simulation after this wall clock time, 0 means never
+	Kernel     KernelConfig     `json:"kernel"`
+	Model      phold.Params     `json:"model"`
+	Output     OutputConfig     `json:"output"`
+	Checkpoint CheckpointConfig `json:"checkpoint"`
+}
+
+type KernelConfig struct {
+	Window       warp.Time `json:"window"`        // limited optimism, 0 means no limit
+	GvtThreshold int       `json:"gvt_threshold"` // length of the logs that starts a GVT evaluation, 0 means warp.TOOLARGE
+	Partition    string    `json:"partition"`     // of the entities: block, roundrobin or hash
+	Rebalance    warp.Time `json:"rebalance"`     // migrate the entities every Rebalance time units, 0 means never
+	Imbalance    float64   `json:"imbalance"`     // of the LP loads that triggers a migration
+	Queue        string    `json:"queue"`         // implementation of the future events: heap or list
+}
+
+type OutputConfig struct {
+	Trace   string `json:"trace"`   // the committed events (.csv, .json or binary)
+	Stats   string `json:"stats"`   // the statistics (.csv or .json)
+	Metrics string `json:"metrics"` // address of the live metrics server (e.g. :9090)
+}
+
+type CheckpointConfig struct {
+	File    string    `json:"file"`    // written every Every time units
+	Every   warp.Time `json:"every"`   // the simulation is run in slices of Every time units
+	Restart string    `json:"restart"` // restart from this checkpoint
+}
+
+/* a time.Duration written as "30s" */
+type Duration time.Duration
+
+/* the parameter Path has an invalid value */
+type FieldError struct {
+	Path string
+	Msg  string
+}
+
+func (e *FieldError) Error() string {
+	return "PHOLD config: " + e.Path + ": " + e.Msg
+}
+
+var partitions = map[string]func(ents, lps int) warp.Partitioner{
+	"block":      func(ents, lps int) warp.Partitioner { return warp.BlockPartition{Entities: ents, LPs: lps} },
+	"roundrobin": func(ents, lps int) warp.Partitioner { return warp.RoundRobinPartition{LPs: lps} },
+	"hash":       func(ents, lps int) warp.Partitioner { return warp.HashPartition{LPs: lps} },
+}
+
+var queues = map[string]int{"heap": warp.QUEUEHEAP, "list": warp.QUEUELIST}
+
+/* the command line flags and the parameters they set, the ones of the model are in phold.Flags */
+type flagPath struct{ name, path, usage string }
+
+var flagPaths = []flagPath{
+	{"lps", "lps", "number of LPs, 0 means one per CPU"},
+	{"seq", "sequential", "run on the sequential reference kernel"},
+	{"timeout", "timeout", "stop the simulation after this wall clock time (e.g. 30s)"},
+	{"window", "kernel.window", "limited optimism: the LPs do not go beyond GVT + window, 0 means no limit"},
+	{"gvt-threshold", "kernel.gvt_threshold", "length of the logs that starts a GVT evaluation"},
+	{"partition", "kernel.partition", "partition of the entities: block, roundrobin or hash"},
+	{"rebalance", "kernel.rebalance", "migrate the entities between the LPs every T time units"},
+	{"imbalance", "kernel.imbalance", "imbalance of the LP loads that triggers a migration"},
+	{"queue", "kernel.queue", "implementation of the future events of the LPs: heap or list"},
+	{"trace", "output.trace", "write the committed events to this file (.csv, .json or binary)"},
+	{"stats", "output.stats", "write the simulation statistics to this file (.csv or .json)"},
+	{"metrics", "output.metrics", "serve the live metrics over HTTP on this address (e.g. :9090)"},
+	{"checkpoint", "checkpoint.file", "write a checkpoint to this file every -every time units"},
+	{"every", "checkpoint.every", "simulated time between two checkpoints"},
+	{"restart", "checkpoint.restart", "restart the simulation from this checkpoint"},
+}
+
+var (
+	configFile = flag.String("config", "", "read the configuration from this JSON file")
+
+	flagValues = map[string]*pathValue{}
+
+	durationType = reflect.TypeOf(Duration(0))
+	phasesType   = reflect.TypeOf([]phold.Phase(nil))
+)
+
+func init() {
+	for _, f := range phold.Flags {
+		if f.Name == "seed" {
+			f.Usage += ", 0 means #LPs + #entities"
+		}
+		flagPaths = append(flagPaths, flagPath{f.Name, "model." + f.Field, f.Usage})
+	}
+
+	def := reflect.ValueOf(defaultConfig())
+	for _, f := range flagPaths {
+		v := field(def, f.path)
+		pv := &pathValue{path: f.path, def: valueString(v), isBool: v.Kind() == reflect.Bool}
+		flagValues[f.name] = pv
+		flag.Var(pv, f.name, f.usage)
+	}
+}
+
+func defaultConfig() Config {
+	m := phold.DefaultParams()
+	m.Seed = 0 // #LPs + #entities, set by loadConfig
+	return Config{
+		Kernel: KernelConfig{GvtThreshold: warp.TOOLARGE, Partition: "block", Imbalance: 0.1, Queue: "heap"},
+		Model:  m,
+	}
+}
+
+/*
+ * reads the configuration from the defaults, the config file, the
+ * environment and the flags, that must have been parsed. The positional
+ * arguments #LPs #ENTITIES, if any, come before the flags
+ */
+func loadConfig() (*Config, error) {
+	c := defaultConfig()
+	if *configFile != "" {
+		if err := readConfigFile(*configFile, &c); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range leafPaths(reflect.TypeOf(c), "") {
+		env := "PHOLD_" + strings.ToUpper(strings.Replace(path, ".", "_", -1))
+		if s, ok := os.LookupEnv(env); ok {
+			if err := setString(&c, path, s); err != nil {
+				return nil, fmt.Errorf("%v (from %s)", err, env)
+			}
+		}
+	}
+
+	switch flag.NArg() {
+	case 0:
+	case 2:
+		for i, path := range []string{"lps", "model.entities"} {
+			if err := setString(&c, path, flag.Arg(i)); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("the positional arguments are #LPs #ENTITIES, not %q", flag.Args())
+	}
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		if pv := flagValues[f.Name]; pv != nil && err == nil {
+			err = setString(&c, pv.path, pv.value)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if c.LPs == 0 {
+		c.LPs = runtime.NumCPU()
+	}
+	if c.Model.Seed == 0 {
+		c.Model.Seed = int64(c.LPs + c.Model.Entities)
+	}
+	if c.Model.Groups == 0 {
+		c.Model.Groups = c.LPs
+	}
+	return &c, c.validate()
+}
+
+func (c *Config) validate() error {
+	if _, ok := queues[c.Kernel.Queue]; !ok {
+		return &FieldError{"kernel.queue", fmt.Sprintf("unknown queue %q, heap or list", c.Kernel.Queue)}
+	}
+	switch {
+	case c.LPs < 0:
+		return &FieldError{"lps", "must not be negative"}
+	case c.Timeout < 0:
+		return &FieldError{"timeout", "must not be negative"}
+	case c.Kernel.Window < 0:
+		return &FieldError{"kernel.window", "must not be negative"}
+	case c.Kernel.GvtThreshold < 1:
+		return &FieldError{"kernel.gvt_threshold", "must be positive"}
+	case partitions[c.Kernel.Partition] == nil:
+		return &FieldError{"kernel.partition", fmt.Sprintf("unknown partition %q", c.Kernel.Partition)}
+	case c.Kernel.Rebalance < 0:
+		return &FieldError{"kernel.rebalance", "must not be negative"}
+	case c.Kernel.Imbalance < 0:
+		return &FieldError{"kernel.imbalance", "must not be negative"}
+	case c.Checkpoint.Every < 0:
+		return &FieldError{"checkpoint.every", "must not be negative"}
+	case c.Checkpoint.File != "" && c.Checkpoint.Every == 0:
+		return &FieldError{"checkpoint.every", "the checkpoints need the time between them"}
+	case c.Checkpoint.Restart != "":
+		return nil // the model is read from the checkpoint
+	}
+	if err := c.Model.Validate(); err != nil {
+		if pe, ok := err.(*phold.ParamError); ok {
+			return &FieldError{"model." + pe.Field, pe.Msg}
+		}
+		return err
+	}
+	return nil
+}
+
+/* a JSON file, the parameters that it does not contain keep their value */
+func readConfigFile(path string, c *Config) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err = json.Unmarshal(b, &doc); err != nil {
+		if se, ok := err.(*json.SyntaxError); ok {
+			err = fmt.Errorf("line %d: %v", 1+strings.Count(string(b[:se.Offset]), "\n"), err)
+		}
+		return fmt.Errorf("PHOLD config %s: %v", path, err)
+	}
+	m, ok := doc.(map[string]interface{})
+	if !ok && doc != nil {
+		return fmt.Errorf("PHOLD config %s: not a mapping of the parameters", path)
+	}
+	return applyMap(reflect.ValueOf(c).Elem(), m, "")
+}
+
+/* sets the fields of the struct v from the decoded document m */
+func applyMap(v reflect.Value, m map[string]interface{}, prefix string) error {
+	for k, val := range m {
+		path := prefix + k
+		f := field(v, k)
+		if !f.IsValid() {
+			return &FieldError{path, "unknown parameter"}
+		}
+		if val == nil {
+			continue // null, the default is kept
+		}
+		switch x := val.(type) {
+		case map[string]interface{}:
+			if f.Kind() != reflect.Struct || f.Type() == durationType {
+				return &FieldError{path, "not a group of parameters"}
+			}
+			if err := applyMap(f, x, path+"."); err != nil {
+				return err
+			}
+		case []interface{}:
+			if f.Type() != phasesType {
+				return &FieldError{path, "not a list"}
+			}
+			phases := make([]phold.Phase, len(x))
+			for i, it := range x {
+				ipath := fmt.Sprintf("%s[%d]", path, i)
+				switch y := it.(type) {
+				case map[string]interface{}:
+					if err := applyMap(reflect.ValueOf(&phases[i]).Elem(), y, ipath+"."); err != nil {
+						return err
+					}
+				case string:
+					ph, err := phold.ParsePhases(y)
+					if err != nil || len(ph) != 1 {
+						return &FieldError{ipath, fmt.Sprintf("invalid phase %q, START:FPOPS", y)}
+					}
+					phases[i] = ph[0]
+				default:
+					return &FieldError{ipath, "a phase is {start, fpops} or START:FPOPS"}
+				}
+			}
+			f.Set(reflect.ValueOf(phases))
+		default:
+			if f.Kind() == reflect.Struct {
+				return &FieldError{path, "a group of parameters, not a value"}
+			}
+			if err := parseInto(f, scalarString(x), path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func scalarString(x interface{}) string {
+	if f, ok := x.(float64); ok {
+		if f == float64(int64(f)) {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return fmt.Sprint(x)
+}
+
+/* sets the parameter path of c from its string representation */
+func setString(c *Config, path, s string) error {
+	f := field(reflect.ValueOf(c).Elem(), path)
+	if !f.IsValid() {
+		return &FieldError{path, "unknown parameter"}
+	}
+	return parseInto(f, s, path)
+}
+
+func parseInto(f reflect.Value, s string, path string) error {
+	switch {
+	case f.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return &FieldError{path, fmt.Sprintf("invalid duration %q, e.g. 30s", s)}
+		}
+		f.SetInt(int64(d))
+		return nil
+	case f.Type() == phasesType:
+		ph, err := phold.ParsePhases(s)
+		if err != nil {
+			return &FieldError{path, err.Error()}
+		}
+		f.Set(reflect.ValueOf(ph))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return &FieldError{path, fmt.Sprintf("expected true or false, not %q", s)}
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return &FieldError{path, fmt.Sprintf("expected an integer, not %q", s)}
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return &FieldError{path, fmt.Sprintf("expected a number, not %q", s)}
+		}
+		f.SetFloat(x)
+	default:
+		return &FieldError{path, "cannot be set"}
+	}
+	return nil
+}
+
+/* the field of the struct v with the JSON path, the zero Value if there is none */
+func field(v reflect.Value, path string) reflect.Value {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct || v.Type() == durationType {
+			return reflect.Value{}
+		}
+		var f reflect.Value
+		for i := 0; i < v.NumField(); i++ {
+			if jsonName(v.Type().Field(i)) == name {
+				f = v.Field(i)
+				break
+			}
+		}
+		if !f.IsValid() {
+			return f
+		}
+		v = f
+	}
+	return v
+}
+
+/* the paths of all the parameters of the struct type t */
+func leafPaths(t reflect.Type, prefix string) []string {
+	var paths []string
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		path := prefix + jsonName(sf)
+		if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			paths = append(paths, leafPaths(sf.Type, path+".")...)
+		} else {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func jsonName(sf reflect.StructField) string {
+	return strings.Split(sf.Tag.Get("json"), ",")[0]
+}
+
+func valueString(v reflect.Value) string {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Type() == phasesType:
+		var s []string
+		for _, ph := range v.Interface().([]phold.Phase) {
+			s = append(s, fmt.Sprintf("%d:%d", ph.Start, ph.FPOps))
+		}
+		return strings.Join(s, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+/* a flag that records its value, that is applied by loadConfig */
+type pathValue struct {
+	path, def, value string
+	isBool           bool
+}
+
+func (v *pathValue) String() string {
+	if v == nil {
+		return ""
+	}
+	if v.value != "" {
+		return v.value
+	}
+	return v.def
+}
+
+func (v *pathValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+func (v *pathValue) IsBoolFlag() bool { return v.isBool }
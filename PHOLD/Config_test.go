@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/jeffallen/go-warp/models/phold"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+/* phold.json documents the defaults */
+func TestConfigFile(t *testing.T) {
+	var c Config
+	if err := readConfigFile("phold.json", &c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Model.Phases) == 0 {
+		c.Model.Phases = nil // [] in the file
+	}
+	if def := defaultConfig(); !reflect.DeepEqual(c, def) {
+		t.Fatalf("phold.json has %+v\nthe defaults are %+v", c, def)
+	}
+
+	f := filepath.Join(t.TempDir(), "c.json")
+	os.WriteFile(f, []byte(`{"lps": 4, "kernel": {"partition": "hash", "queue": "list"},
+		"model": {"remote": 0.5, "seed": null, "phases": [{"start": 0, "fpops": 10}, "50:20"]},
+		"output": {"metrics": ":9090"}}`), 0666)
+	c = defaultConfig()
+	if err := readConfigFile(f, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.LPs != 4 || c.Kernel.Partition != "hash" || c.Kernel.Queue != "list" || c.Model.Remote != 0.5 ||
+		c.Model.Density != 0.5 || c.Output.Metrics != ":9090" ||
+		!reflect.DeepEqual(c.Model.Phases, []phold.Phase{{Start: 0, FPOps: 10}, {Start: 50, FPOps: 20}}) {
+		t.Fatalf("wrong configuration %+v", c)
+	}
+}
+
+/* the errors point at the bad parameter */
+func TestConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	for doc, path := range map[string]string{
+		`{"model": {"densty": 1}}`:                                  "model.densty",
+		`{"model": {"entities": "many"}}`:                           "model.entities",
+		`{"kernel": 3}`:                                             "kernel",
+		`{"timeout": "soon"}`:                                       "timeout",
+		`{"model": {"phases": [{"start": 1, "x": 2}]}}`:             "model.phases[0].x",
+		`{"model": {"entities": 10, "hot": 2}}`:                     "model.hot",
+		`{"model": {"entities": 10}, "kernel": {"partition": "x"}}`: "kernel.partition",
+		`{"model": {"entities": 10}, "kernel": {"queue": "tree"}}`:  "kernel.queue",
+		`{"model": {"entities": 10, "phases": ["0:-5"]}}`:           "model.phases[0].fpops",
+	} {
+		f := filepath.Join(dir, "c.json")
+		os.WriteFile(f, []byte(doc), 0666)
+		c := defaultConfig()
+		err := readConfigFile(f, &c)
+		if err == nil {
+			c.LPs, c.Model.Groups, c.Model.Seed = 1, 1, 2 // as loadConfig
+			err = c.validate()
+		}
+		fe, ok := err.(*FieldError)
+		if !ok || fe.Path != path {
+			t.Errorf("%s: error %v, want one for %s", doc, err, path)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package lcg16807
+
+/*
+ * DISTRIBUTIONS
+ *
+ * the distributions are implemented by the rng package for all the
+ * generators, see rng/dist.go: every one consumes a fixed number of draws
+ * of RandFloat, so the draws of an event can be undone with Jump. The RNG
+ * is wrapped in an rng.Rand on the stack, so a draw does not allocate.
+ */
+
+import (
+	"github.com/jeffallen/go-warp/rng"
+)
+
+const POISSONMAX = rng.POISSONMAX
+
+type Empirical = rng.Empirical
+
+func NewEmpirical(weights []float64) *Empirical {
+	return rng.NewEmpirical(weights)
+}
+
+func NewZipf(n int, s float64) *Empirical {
+	return rng.NewZipf(n, s)
+}
+
+func (r *RNG) RandUniform(a, b float64) float64 {
+	d := rng.Rand{Generator: r}
+	return d.RandUniform(a, b)
+}
+
+func (r *RNG) RandExponential(mean float64) float64 {
+	d := rng.Rand{Generator: r}
+	return d.RandExponential(mean)
+}
+
+func (r *RNG) RandNormal(mu, sigma float64) float64 {
+	d := rng.Rand{Generator: r}
+	return d.RandNormal(mu, sigma)
+}
+
+func (r *RNG) RandLogNormal(mu, sigma float64) float64 {
+	d := rng.Rand{Generator: r}
+	return d.RandLogNormal(mu, sigma)
+}
+
+func (r *RNG) RandPoisson(lambda float64) int {
+	d := rng.Rand{Generator: r}
+	return d.RandPoisson(lambda)
+}
+
+func (r *RNG) RandGeometric(p float64) int {
+	d := rng.Rand{Generator: r}
+	return d.RandGeometric(p)
+}
+
+func (r *RNG) RandWeibull(shape, scale float64) float64 {
+	d := rng.Rand{Generator: r}
+	return d.RandWeibull(shape, scale)
+}
+
+func (r *RNG) RandPareto(xm, alpha float64) float64 {
+	d := rng.Rand{Generator: r}
+	return d.RandPareto(xm, alpha)
+}
+
+/* consumes k draws */
+func (r *RNG) RandErlang(k int, rate float64) float64 {
+	d := rng.Rand{Generator: r}
+	return d.RandErlang(k, rate)
+}
+
+func (r *RNG) RandEmpirical(e *Empirical) int {
+	d := rng.Rand{Generator: r}
+	return d.RandEmpirical(e)
+}
@@ -0,0 +1,125 @@
+package lcg16807
+
+import (
+	"math"
+	"testing"
+)
+
+const nSamples = 200000
+
+/* the sample mean and variance must be within 5 standard errors of the expected values */
+func checkMoments(t *testing.T, name string, draws int64, f func(r *RNG) float64, mean, variance float64) {
+	r := NewStream(12345, 1)
+	var s, s2 float64
+	for i := 0; i < nSamples; i++ {
+		n := r.N
+		x := f(r)
+		if r.N-n != draws {
+			t.Fatalf("%s: %d draws, want %d", name, r.N-n, draws)
+		}
+		s += x
+		s2 += x * x
+	}
+	m := s / nSamples
+	v := s2/nSamples - m*m
+	if se := math.Sqrt(variance / nSamples); math.Abs(m-mean) > 5*se {
+		t.Errorf("%s: mean %g, want %g", name, m, mean)
+	}
+	if math.Abs(v-variance) > 0.05*variance {
+		t.Errorf("%s: variance %g, want %g", name, v, variance)
+	}
+}
+
+func TestContinuous(t *testing.T) {
+	checkMoments(t, "uniform", 1, func(r *RNG) float64 { return r.RandUniform(2, 6) }, 4, 16.0/12)
+	checkMoments(t, "exponential", 1, func(r *RNG) float64 { return r.RandExponential(3) }, 3, 9)
+	checkMoments(t, "normal", 1, func(r *RNG) float64 { return r.RandNormal(10, 2) }, 10, 4)
+
+	mu, sigma := 0.5, 0.4
+	lm := math.Exp(mu + sigma*sigma/2)
+	checkMoments(t, "lognormal", 1, func(r *RNG) float64 { return r.RandLogNormal(mu, sigma) },
+		lm, (math.Exp(sigma*sigma)-1)*lm*lm)
+
+	k, l := 2.0, 3.0
+	g1, g2 := math.Gamma(1+1/k), math.Gamma(1+2/k)
+	checkMoments(t, "weibull", 1, func(r *RNG) float64 { return r.RandWeibull(k, l) }, l*g1, l*l*(g2-g1*g1))
+
+	xm, a := 1.0, 5.0
+	checkMoments(t, "pareto", 1, func(r *RNG) float64 { return r.RandPareto(xm, a) },
+		a*xm/(a-1), xm*xm*a/((a-1)*(a-1)*(a-2)))
+
+	checkMoments(t, "erlang", 4, func(r *RNG) float64 { return r.RandErlang(4, 2) }, 2, 1)
+}
+
+func TestDiscrete(t *testing.T) {
+	for _, l := range []float64{0.5, 7, 80, 2000} {
+		checkMoments(t, "poisson", 1, func(r *RNG) float64 { return float64(r.RandPoisson(l)) }, l, l)
+	}
+	p := 0.2
+	checkMoments(t, "geometric", 1, func(r *RNG) float64 { return float64(r.RandGeometric(p)) }, (1-p)/p, (1-p)/(p*p))
+	if x := NewStream(1, 0).RandGeometric(1); x != 0 {
+		t.Fatal("geometric with p = 1:", x)
+	}
+}
+
+/* the frequencies of the values must pass the chi-square test */
+func checkFrequencies(t *testing.T, name string, e *Empirical, prob []float64) {
+	r := NewStream(777, 0)
+	count := make([]int, e.Len())
+	for i := 0; i < nSamples; i++ {
+		n := r.N
+		count[r.RandEmpirical(e)]++
+		if r.N-n != 1 {
+			t.Fatalf("%s: %d draws", name, r.N-n)
+		}
+	}
+
+	chi2, df := 0.0, -1
+	for i := range prob {
+		exp := prob[i] * nSamples
+		if exp == 0 {
+			if count[i] > 0 {
+				t.Fatalf("%s: %d samples of %d, that has probability 0", name, count[i], i)
+			}
+			continue
+		}
+		chi2 += (float64(count[i]) - exp) * (float64(count[i]) - exp) / exp
+		df++
+	}
+	/* the 0.999 quantile of the chi-square distribution is below df + 4.5 sqrt(df) + 10 */
+	if chi2 > float64(df)+4.5*math.Sqrt(float64(df))+10 {
+		t.Errorf("%s: chi-square %g with %d degrees of freedom", name, chi2, df)
+	}
+}
+
+func TestEmpirical(t *testing.T) {
+	w := []float64{1, 0, 3, 6, 0}
+	checkFrequencies(t, "empirical", NewEmpirical(w), []float64{0.1, 0, 0.3, 0.6, 0})
+
+	n, s := 50, 1.2
+	prob := make([]float64, n)
+	h := 0.0
+	for i := range prob {
+		prob[i] = math.Pow(float64(i+1), -s)
+		h += prob[i]
+	}
+	for i := range prob {
+		prob[i] /= h
+	}
+	checkFrequencies(t, "zipf", NewZipf(n, s), prob)
+}
+
+/* the distributions are drawn by every event, they must not allocate */
+func TestAllocs(t *testing.T) {
+	r := NewStream(1, 0)
+	e := NewZipf(10, 1)
+	n := testing.AllocsPerRun(100, func() {
+		r.RandNormal(0, 1)
+		r.RandPoisson(3)
+		r.RandErlang(2, 1)
+		r.RandEmpirical(e)
+	})
+	if n != 0 {
+		t.Fatalf("%v allocations", n)
+	}
+}
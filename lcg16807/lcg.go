@@ -21,7 +21,10 @@ import (
  * Linear Congruential Generator LGC 16807
  */
 
-type RNG struct{ Seed, Prev int64 }
+type RNG struct {
+	Seed, Prev int64
+	N          int64 // number of draws since the start of the stream
+}
 
 const (
 	module int64 = 1<<31 - 1 // RNG module
@@ -32,21 +35,27 @@ const (
 func RandInit(seed int64) *RNG {
 	var rngptr *RNG = new(RNG)
 
+	/* with a seed multiple of the module every draw would be 0 */
+	seed %= module
+	if seed < 0 {
+		seed += module
+	}
 	if seed == 0 {
 		panic("Seed must not be zero.")
 	}
 
-	*rngptr = RNG{seed, seed}
+	*rngptr = RNG{Seed: seed, Prev: seed}
 	return rngptr
 }
 
-/* RandFloat generates a random float64 in the range 0..1 */
+/* RandFloat generates a random float64 in the range (0, 1), both excluded */
 func (rng *RNG) RandFloat() float64 {
 	var n int64
 	var fl float64
 
 	n = (coeff * (rng.Prev)) % module
 	rng.Prev = n
+	rng.N++
 
 	fl = float64(n) / float64(module)
 
@@ -33,3 +33,20 @@ func TestRandIntUniform(t *testing.T) {
 		t.Fatal("mean too far from expected:", avg, diff)
 	}
 }
+
+/* the seed is reduced modulo 2^31 - 1, a multiple of it is rejected */
+func TestSeed(t *testing.T) {
+	r := RandInit(-5)
+	for i := 0; i < 1000; i++ {
+		if f := r.RandFloat(); f <= 0 || f >= 1 {
+			t.Fatal("out of limits: ", f)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic with seed 2^31 - 1")
+		}
+	}()
+	RandInit(1<<31 - 1)
+}
@@ -0,0 +1,119 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package lcg16807
+
+/*
+ * STREAMS
+ *
+ * the sequence of the generator is split in streams of STREAMLEN draws:
+ * the generators of different streams with the same seed never produce
+ * the same numbers, as long as each one draws at most STREAMLEN numbers.
+ * The generator can also jump ahead (or back) of any number of draws, so
+ * that the draws can be undone. RNG implements rng.Generator, but its
+ * period is short: the generators of the rng package are better suited
+ * to long simulations
+ */
+
+import (
+	"errors"
+	"github.com/jeffallen/go-warp/rng"
+)
+
+const (
+	STREAMLEN    int64 = 1 << 18                  // draws of a stream
+	SUBSTREAMLEN int64 = 1 << 12                  // draws of a substream
+	MAXSTREAMS         = (module - 1) / STREAMLEN // streams in the period of the generator
+)
+
+/* b^e mod module, for e >= 0 */
+func powMod(b, e int64) int64 {
+	r := int64(1)
+	for b %= module; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = r * b % module
+		}
+		b = b * b % module
+	}
+	return r
+}
+
+/* returns the generator of stream i, starting from seed */
+func NewStream(seed int64, i int) *RNG {
+	if i < 0 || int64(i) >= MAXSTREAMS {
+		panic("Stream out of range.")
+	}
+	rng := RandInit(seed)
+	rng.Jump(int64(i) * STREAMLEN)
+	rng.N = 0
+	return rng
+}
+
+/*
+ * moves the generator n draws ahead, or back if n is negative: after
+ * Jump(-k) the next k draws are the same as the last k
+ */
+func (rng *RNG) Jump(n int64) {
+	e := n % (module - 1) // the period of the generator is module - 1
+	if e < 0 {
+		e += module - 1
+	}
+	rng.Prev = rng.Prev * powMod(coeff, e) % module
+	rng.N += n
+}
+
+/* undoes the last draw */
+func (rng *RNG) Back() {
+	rng.Jump(-1)
+}
+
+/* the first n streams, it is an rng.Source */
+func Streams(seed int64, n int) []rng.Generator {
+	ret := make([]rng.Generator, n)
+	for i := range ret {
+		ret[i] = NewStream(seed, i)
+	}
+	return ret
+}
+
+func (r *RNG) Float64() float64 {
+	return r.RandFloat()
+}
+
+/* consumes 3 draws */
+func (r *RNG) Uint64() uint64 {
+	a, b, c := r.next(), r.next(), r.next()
+	return a<<33 ^ b<<2 ^ c>>29
+}
+
+func (r *RNG) next() uint64 {
+	r.RandFloat()
+	return uint64(r.Prev)
+}
+
+/* the substreams start every SUBSTREAMLEN draws of the stream */
+func (r *RNG) NextSubstream() {
+	r.Jump(SUBSTREAMLEN - r.N%SUBSTREAMLEN)
+}
+
+func (r *RNG) AppendState(s []uint64) []uint64 {
+	return append(s, uint64(r.Seed), uint64(r.Prev), uint64(r.N))
+}
+
+func (r *RNG) SetState(s []uint64) error {
+	if len(s) != 3 || s[1] == 0 || s[1] >= uint64(module) {
+		return errors.New("lcg16807: invalid state")
+	}
+	r.Seed, r.Prev, r.N = int64(s[0]), int64(s[1]), int64(s[2])
+	return nil
+}
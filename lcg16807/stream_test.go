@@ -0,0 +1,48 @@
+package lcg16807
+
+import (
+	"testing"
+)
+
+func TestJump(t *testing.T) {
+	r, j := RandInit(7), RandInit(7)
+	for i := 0; i < 12345; i++ {
+		r.RandFloat()
+	}
+	j.Jump(12345)
+	if *r != *j {
+		t.Fatalf("jump ahead: %+v, want %+v", *j, *r)
+	}
+
+	f := r.RandFloat()
+	r.Back()
+	if g := r.RandFloat(); g != f {
+		t.Fatal("the draw after Back is", g, "want", f)
+	}
+
+	r.Jump(-12346)
+	if r.Prev != 7 || r.N != 0 {
+		t.Fatalf("jump back to the seed: %+v", *r)
+	}
+}
+
+func TestStreams(t *testing.T) {
+	s := NewStream(3, 0)
+	s.Jump(STREAMLEN)
+	if next := NewStream(3, 1); s.Prev != next.Prev {
+		t.Fatal("stream 1 does not start at the end of stream 0")
+	}
+
+	/* the first draws of the streams are all different */
+	seen := make(map[int64]int)
+	for i := 0; i < 1000; i++ {
+		s := NewStream(3, i)
+		for k := 0; k < 100; k++ {
+			s.RandFloat()
+			if j, ok := seen[s.Prev]; ok {
+				t.Fatalf("streams %d and %d overlap", j, i)
+			}
+			seen[s.Prev] = i
+		}
+	}
+}
@@ -0,0 +1,309 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+/*
+ * the airport network: the airports (the entities) are the vertices of a
+ * graph, whose edges are the routes. An aircraft arrives at an airport,
+ * waits for a runway and lands, taxis and turns around on the ground, waits
+ * for a runway again, takes off and flies to a neighbour airport, chosen
+ * with a probability proportional to its number of routes: the hubs get
+ * more traffic, so the communication is not uniform. The landings have
+ * priority over the takeoffs. The times are in seconds.
+ *
+ * As in the PCS model, the time of an event is rounded up to the slot of
+ * its kind (see SLOTS): the events of an airport with the same time have
+ * the same kind, and their order does not change the state of the airport,
+ * so the sequential and the parallel runs have the same statistics.
+ */
+package airport
+
+import (
+	"fmt"
+	"github.com/jeffallen/go-warp/warp"
+)
+
+/* the kinds of the events (Info.Flag), that are also the slots of their times */
+const (
+	ARRIVAL = iota // an aircraft asks for a runway to land
+	LANDED         // an aircraft has landed, its runway is free
+	READY          // an aircraft asks for a runway to take off
+	TAKEOFF        // an aircraft has taken off, its runway is free
+	SLOTS
+)
+
+const OVERHEAD = 1200 // seconds of a flight spent in the climb and the approach
+
+type Params struct {
+	Airports int       `json:"airports"`
+	Shape    string    `json:"shape"`    // of the graph of the routes, see Shapes
+	Degree   int       `json:"degree"`   // mean routes per airport of the "random" shape
+	Planes   int       `json:"planes"`   // aircraft on the ground at every airport at the start
+	Runways  int       `json:"runways"`  // of every airport
+	Land     float64   `json:"land"`     // mean runway time of a landing
+	Takeoff  float64   `json:"takeoff"`  // mean runway time of a takeoff
+	Ground   float64   `json:"ground"`   // mean time between the landing and the takeoff request
+	Speed    float64   `json:"speed"`    // cruise speed, km/s
+	Size     float64   `json:"size"`     // km, the airports are in a square with this side
+	Seed     int64     `json:"seed"`     // of the graph and of the positions of the airports
+	EndTime  warp.Time `json:"end_time"` // simulated time
+}
+
+/* the counters of an airport, or of the whole network */
+type Stats struct {
+	Arrivals    int     `json:"arrivals"`
+	Landings    int     `json:"landings"`
+	Takeoffs    int     `json:"takeoffs"`
+	LandingWait float64 `json:"landing_wait"` // aircraft * seconds spent waiting to land
+	TakeoffWait float64 `json:"takeoff_wait"` // aircraft * seconds spent waiting to take off
+	MaxQueue    int     `json:"max_queue"`    // longest landing queue
+}
+
+/* an airport, implements warp.Entity */
+type Airport struct {
+	Busy         int       // runways
+	LandingQueue int       // aircraft waiting to land
+	TakeoffQueue int       // aircraft waiting to take off
+	Last         warp.Time // of the last event, for the waiting times
+	Stats
+}
+
+/* an invalid parameter, Field is its JSON name */
+type ParamError struct {
+	Field string
+	Msg   string
+}
+
+func (e *ParamError) Error() string {
+	return "AIRPORT: " + e.Field + ": " + e.Msg
+}
+
+func paramError(field, format string, a ...interface{}) error {
+	return &ParamError{field, fmt.Sprintf(format, a...)}
+}
+
+func DefaultParams() Params {
+	return Params{Airports: 64, Shape: "random", Degree: 4, Planes: 8, Runways: 2, Land: 90, Takeoff: 60,
+		Ground: 2700, Speed: 0.23, Size: 3000, Seed: 1, EndTime: 3 * 86400}
+}
+
+var (
+	params   Params
+	airports []Airport
+	routes   *Routes
+)
+
+func (p *Params) Validate() error {
+	if p.Airports < 2 {
+		return paramError("airports", "at least 2 airports are needed, not %d", p.Airports)
+	}
+	if Shapes[p.Shape] == nil {
+		return paramError("shape", "unknown shape %q", p.Shape)
+	}
+	if p.Shape == "random" && (p.Degree < 2 || p.Degree >= p.Airports) {
+		return paramError("degree", "must be in [2, %d), not %d", p.Airports, p.Degree)
+	}
+	if p.Planes < 0 {
+		return paramError("planes", "must not be negative, not %d", p.Planes)
+	}
+	if p.Runways < 1 {
+		return paramError("runways", "must be positive, not %d", p.Runways)
+	}
+	for _, f := range []struct {
+		name string
+		v    float64
+	}{{"land", p.Land}, {"takeoff", p.Takeoff}, {"ground", p.Ground}, {"speed", p.Speed}, {"size", p.Size}} {
+		if f.v <= 0 {
+			return paramError(f.name, "must be positive, not %v", f.v)
+		}
+	}
+	if p.EndTime <= 0 {
+		return paramError("end_time", "must be positive, not %d", p.EndTime)
+	}
+	return nil
+}
+
+/* builds the routes and prepares the airports of a new simulation */
+func Setup(p Params) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	params = p
+	routes = NewRoutes(p)
+	airports = make([]Airport, p.Airports)
+	return nil
+}
+
+/* the parameters of the current simulation */
+func Current() Params {
+	return params
+}
+
+/* the routes of the current simulation */
+func CurrentRoutes() *Routes {
+	return routes
+}
+
+/*
+ * runs the airport network with the parameters p, the kernel options
+ * (LPs, Sequential, Context, Seed, Generator, Partition, Window) are taken
+ * from cfg. If cfg.Partition is nil the airports are partitioned by the
+ * graph of the routes (see Routes.Graph)
+ */
+func Run(cfg warp.Config, p Params) (*warp.Result, error) {
+	if err := Setup(p); err != nil {
+		return nil, err
+	}
+	if cfg.Partition == nil && cfg.LPs > 1 {
+		cfg.Partition = routes.Graph().Partition(cfg.LPs, 0.1)
+	}
+	cfg.EndTime = p.EndTime
+	cfg.Entities = Entities()
+	return warp.Run(cfg, InitLP)
+}
+
+func Entities() []warp.Entity {
+	ents := make([]warp.Entity, len(airports))
+	for i := range ents {
+		ents[i] = &airports[i]
+	}
+	return ents
+}
+
+/* the aircraft of the airports of the LP ask for a runway in the first hour */
+func InitLP(l *warp.LocalData) error {
+	for a := range airports {
+		if warp.EntityLP(a) != l.IndexLP {
+			continue
+		}
+		r := warp.EntityStream(a)
+		for i := 0; i < params.Planes; i++ {
+			t := at(0, r.RandUniform(0, 3600), READY)
+			l.NewEvent(warp.CreateEvent(0, t, warp.Info{From: a, To: a, Flag: READY}))
+		}
+	}
+	return nil
+}
+
+/* the state of the airports, once the simulation is over */
+func Airports() []Airport {
+	return airports
+}
+
+/* the counters of the whole network */
+func Totals() Stats {
+	var s Stats
+	for i := range airports {
+		t := &airports[i].Stats
+		s.Arrivals += t.Arrivals
+		s.Landings += t.Landings
+		s.Takeoffs += t.Takeoffs
+		s.LandingWait += t.LandingWait
+		s.TakeoffWait += t.TakeoffWait
+		if t.MaxQueue > s.MaxQueue {
+			s.MaxQueue = t.MaxQueue
+		}
+	}
+	return s
+}
+
+/* mean seconds spent waiting for a runway by a landing and by a takeoff */
+func (s *Stats) MeanWaits() (float64, float64) {
+	var l, t float64
+	if s.Landings > 0 {
+		l = s.LandingWait / float64(s.Landings)
+	}
+	if s.Takeoffs > 0 {
+		t = s.TakeoffWait / float64(s.Takeoffs)
+	}
+	return l, t
+}
+
+func (a *Airport) Handle(ev *warp.Event, l *warp.LocalData) {
+	r := l.Rand()
+	self := ev.Type.To
+	a.wait(ev.Time)
+
+	switch ev.Type.Flag {
+	case ARRIVAL:
+		a.Arrivals++
+		if a.Busy < params.Runways {
+			a.Busy++
+			a.schedule(self, ev.Time, r.RandExponential(params.Land), LANDED, l)
+		} else {
+			a.LandingQueue++
+			if a.LandingQueue > a.MaxQueue {
+				a.MaxQueue = a.LandingQueue
+			}
+		}
+	case LANDED:
+		a.Landings++
+		a.schedule(self, ev.Time, r.RandExponential(params.Ground), READY, l)
+		a.release(self, ev.Time, l)
+	case READY:
+		if a.Busy < params.Runways {
+			a.Busy++
+			a.schedule(self, ev.Time, r.RandExponential(params.Takeoff), TAKEOFF, l)
+		} else {
+			a.TakeoffQueue++
+		}
+	case TAKEOFF:
+		a.Takeoffs++
+		dest := routes.Choose(self, r.RandFloat())
+		a.schedule(dest, ev.Time, routes.FlightTime(self, dest), ARRIVAL, l)
+		a.release(self, ev.Time, l)
+	default:
+		l.Fail(fmt.Errorf("unknown event kind %d for the airport %d", ev.Type.Flag, ev.Type.To))
+	}
+}
+
+/* a runway is free: the landings first */
+func (a *Airport) release(self int, t warp.Time, l *warp.LocalData) {
+	r := l.Rand()
+	switch {
+	case a.LandingQueue > 0:
+		a.LandingQueue--
+		a.schedule(self, t, r.RandExponential(params.Land), LANDED, l)
+	case a.TakeoffQueue > 0:
+		a.TakeoffQueue--
+		a.schedule(self, t, r.RandExponential(params.Takeoff), TAKEOFF, l)
+	default:
+		a.Busy--
+	}
+}
+
+/* adds the waiting since the last event */
+func (a *Airport) wait(t warp.Time) {
+	a.LandingWait += float64(a.LandingQueue) * float64(t-a.Last)
+	a.TakeoffWait += float64(a.TakeoffQueue) * float64(t-a.Last)
+	a.Last = t
+}
+
+func (a *Airport) schedule(to int, t warp.Time, d float64, k int32, l *warp.LocalData) {
+	if err := warp.NoticeEntity(warp.CreateEvent(0, at(t, d, k), warp.Info{Flag: k}), to, l); err != nil {
+		l.Fail(fmt.Errorf("event not sent to the airport %d: %w", to, err))
+	}
+}
+
+func (a *Airport) Save() interface{} {
+	return *a
+}
+
+func (a *Airport) Restore(s interface{}) {
+	*a = s.(Airport)
+}
+
+/* the first time after t + d (at least t + 1) in the slot of kind k */
+func at(t warp.Time, d float64, k int32) warp.Time {
+	base := t + 1 + warp.Time(d)
+	return base + (warp.Time(k)-base%SLOTS+SLOTS)%SLOTS
+}
@@ -0,0 +1,69 @@
+package airport
+
+import (
+	"github.com/jeffallen/go-warp/warp"
+	"github.com/jeffallen/go-warp/warp/warptest"
+	"testing"
+)
+
+func TestShapes(t *testing.T) {
+	p := DefaultParams()
+	p.Airports = 12
+	degrees := map[string][2]int{"ring": {2, 2}, "grid": {2, 4}, "star": {1, 11}, "complete": {11, 11}, "random": {1, 11}}
+	for shape, d := range degrees {
+		p.Shape = shape
+		rt := NewRoutes(p)
+
+		/* connected, within the degrees of the shape, and the probabilities sum to 1 */
+		seen := map[int]bool{0: true}
+		stack := []int{0}
+		for len(stack) > 0 {
+			a := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			sum := 0.0
+			for _, b := range rt.To[a] {
+				sum += rt.Prob(a, b)
+				if !seen[b] {
+					seen[b] = true
+					stack = append(stack, b)
+				}
+			}
+			if len(rt.To[a]) < d[0] || len(rt.To[a]) > d[1] || sum < 0.999999 || sum > 1.000001 {
+				t.Fatalf("%s: airport %d has routes %v, probability %v", shape, a, rt.To[a], sum)
+			}
+		}
+		if len(seen) != p.Airports {
+			t.Fatalf("%s: %d airports reachable from 0", shape, len(seen))
+		}
+	}
+}
+
+/*
+ * the airports of the three shapes of the network: the traffic flows as
+ * expected (a takeoff before its arrival, an arrival before its landing,
+ * the hub of the star with most of the arrivals) and the parallel runs end
+ * with the counters and the queues of the sequential one
+ */
+func TestRegression(t *testing.T) {
+	for _, shape := range []string{"random", "star", "grid"} {
+		p := DefaultParams()
+		p.Airports, p.Shape, p.EndTime = 24, shape, 86400
+
+		if !t.Run(shape, func(t *testing.T) {
+			warptest.Compare(t, warp.Config{LPs: 4, Seed: 3, Window: 4 * 3600},
+				[]warp.Partitioner{nil, warp.RoundRobinPartition{LPs: 4}},
+				func(cfg warp.Config) (*warp.Result, error) { return Run(cfg, p) },
+				func() interface{} { return append([]Airport(nil), Airports()...) })
+		}) {
+			continue
+		}
+
+		s := Totals()
+		if s.Takeoffs < s.Arrivals || s.Arrivals < s.Landings || s.LandingWait == 0 {
+			t.Fatalf("%s: %+v", shape, s)
+		}
+		if shape == "star" && airports[0].Arrivals*2 < s.Arrivals-1 {
+			t.Fatalf("star: the hub has %d of %d arrivals", airports[0].Arrivals, s.Arrivals)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package airport
+
+/*
+ * ROUTES
+ *
+ * the airports are placed in a square of side Size and connected by the
+ * routes of a graph of the given shape: the flight time of a route is
+ * OVERHEAD plus the distance at the cruise speed. The graph and the
+ * positions depend on Seed only, so every run with the same parameters
+ * has the same network.
+ */
+
+import (
+	"github.com/jeffallen/go-warp/rng"
+	"github.com/jeffallen/go-warp/warp"
+	"math"
+	"sort"
+)
+
+type Routes struct {
+	X, Y  []float64 // positions of the airports, km
+	To    [][]int   // To[a] are the neighbours of airport a, in increasing order
+	cumul [][]float64
+	speed float64
+}
+
+/* builds the edges of a shape, given the positions and the parameters */
+type Shape func(p Params, r *rng.Rand, x, y []float64) [][2]int
+
+var Shapes = map[string]Shape{
+	"ring":     ring,
+	"grid":     grid,
+	"star":     star,
+	"random":   random,
+	"complete": complete,
+}
+
+func NewRoutes(p Params) *Routes {
+	n := p.Airports
+	r := rng.New(rng.MRG32k3aStreams(p.Seed, 1)[0])
+	rt := &Routes{X: make([]float64, n), Y: make([]float64, n), To: make([][]int, n), speed: p.Speed}
+
+	adj := make([]map[int]bool, n)
+	for a := range adj {
+		adj[a] = make(map[int]bool)
+	}
+	for _, e := range Shapes[p.Shape](p, r, rt.X, rt.Y) {
+		if e[0] != e[1] {
+			adj[e[0]][e[1]] = true
+			adj[e[1]][e[0]] = true
+		}
+	}
+	for a := range adj {
+		for b := range adj[a] {
+			rt.To[a] = append(rt.To[a], b)
+		}
+		sort.Ints(rt.To[a])
+	}
+
+	/* a destination is chosen with a probability proportional to its number of routes */
+	rt.cumul = make([][]float64, n)
+	for a := range rt.To {
+		sum := 0.0
+		for _, b := range rt.To[a] {
+			sum += float64(len(rt.To[b]))
+		}
+		acc := 0.0
+		for _, b := range rt.To[a] {
+			acc += float64(len(rt.To[b])) / sum
+			rt.cumul[a] = append(rt.cumul[a], acc)
+		}
+	}
+	return rt
+}
+
+/* the destination of a flight from a, given a uniform number u in [0, 1) */
+func (rt *Routes) Choose(a int, u float64) int {
+	for i, c := range rt.cumul[a] {
+		if u < c {
+			return rt.To[a][i]
+		}
+	}
+	return rt.To[a][len(rt.To[a])-1]
+}
+
+/* the probability that a flight from a goes to b */
+func (rt *Routes) Prob(a, b int) float64 {
+	prev := 0.0
+	for i, c := range rt.cumul[a] {
+		if rt.To[a][i] == b {
+			return c - prev
+		}
+		prev = c
+	}
+	return 0
+}
+
+/* seconds of the flight from a to b */
+func (rt *Routes) FlightTime(a, b int) float64 {
+	return OVERHEAD + math.Hypot(rt.X[a]-rt.X[b], rt.Y[a]-rt.Y[b])/rt.speed
+}
+
+/*
+ * the communication graph of the model: the weight of an airport is its
+ * share of the traffic (the airports with more routes get more flights),
+ * the weight of a route is the expected share of the flights on it
+ */
+func (rt *Routes) Graph() *warp.Graph {
+	g := warp.NewGraph(len(rt.To))
+	for a := range rt.To {
+		g.SetWeight(a, len(rt.To[a]))
+		for _, b := range rt.To[a] {
+			if a < b {
+				g.AddEdge(a, b, int(100*(rt.Prob(a, b)+rt.Prob(b, a)))+1)
+			}
+		}
+	}
+	return g
+}
+
+/* the airports on a circle, each one connected to the next */
+func ring(p Params, r *rng.Rand, x, y []float64) [][2]int {
+	var e [][2]int
+	n := len(x)
+	for i := range x {
+		phi := 2 * math.Pi * float64(i) / float64(n)
+		x[i], y[i] = p.Size/2*(1+math.Cos(phi)), p.Size/2*(1+math.Sin(phi))
+		e = append(e, [2]int{i, (i + 1) % n})
+	}
+	return e
+}
+
+/* rows x cols airports, rows is the largest divisor of the airports not above their square root */
+func grid(p Params, r *rng.Rand, x, y []float64) [][2]int {
+	var e [][2]int
+	n := len(x)
+	rows := int(math.Sqrt(float64(n)))
+	for n%rows != 0 {
+		rows--
+	}
+	cols := n / rows
+	for i := range x {
+		row, col := i/cols, i%cols
+		x[i], y[i] = p.Size*(float64(col)+0.5)/float64(cols), p.Size*(float64(row)+0.5)/float64(rows)
+		if col+1 < cols {
+			e = append(e, [2]int{i, i + 1})
+		}
+		if row+1 < rows {
+			e = append(e, [2]int{i, i + cols})
+		}
+	}
+	return e
+}
+
+/* airport 0 is the hub, in the center, the others are on a circle */
+func star(p Params, r *rng.Rand, x, y []float64) [][2]int {
+	var e [][2]int
+	n := len(x)
+	x[0], y[0] = p.Size/2, p.Size/2
+	for i := 1; i < n; i++ {
+		phi := 2 * math.Pi * float64(i) / float64(n-1)
+		x[i], y[i] = p.Size/2*(1+math.Cos(phi)), p.Size/2*(1+math.Sin(phi))
+		e = append(e, [2]int{0, i})
+	}
+	return e
+}
+
+/*
+ * random positions; a random tree keeps the graph connected, then random
+ * routes are added up to Degree routes per airport on average
+ */
+func random(p Params, r *rng.Rand, x, y []float64) [][2]int {
+	var e [][2]int
+	n := len(x)
+	seen := make(map[[2]int]bool)
+	add := func(a, b int) {
+		if a > b {
+			a, b = b, a
+		}
+		if a != b && !seen[[2]int{a, b}] {
+			seen[[2]int{a, b}] = true
+			e = append(e, [2]int{a, b})
+		}
+	}
+	for i := range x {
+		x[i], y[i] = r.RandUniform(0, p.Size), r.RandUniform(0, p.Size)
+		if i > 0 {
+			add(i, int(r.RandIntUniform(0, int32(i-1))))
+		}
+	}
+	for len(e) < n*p.Degree/2 {
+		add(int(r.RandIntUniform(0, int32(n-1))), int(r.RandIntUniform(0, int32(n-1))))
+	}
+	return e
+}
+
+/* every airport is connected to all the others, on a circle */
+func complete(p Params, r *rng.Rand, x, y []float64) [][2]int {
+	var e [][2]int
+	ring(p, r, x, y)
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			e = append(e, [2]int{i, j})
+		}
+	}
+	return e
+}
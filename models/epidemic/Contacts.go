@@ -0,0 +1,97 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package epidemic
+
+/*
+ * CONTACTS
+ *
+ * the contact network is a small world (Watts-Strogatz): the individuals
+ * are on a ring, each one connected to the Degree/2 following ones, then
+ * every contact is rewired with probability Rewire to a random individual.
+ * Most of the contacts stay between close individuals, that the block
+ * partition places on the same LP. The network and the initial infections
+ * depend on Seed only.
+ */
+
+import (
+	"github.com/jeffallen/go-warp/rng"
+	"sort"
+)
+
+type Contacts struct {
+	adj     [][]int32 // adj[i] are the contacts of individual i, in increasing order
+	Initial []int     // the individuals infected at the start, in increasing order
+}
+
+func NewContacts(p Params) *Contacts {
+	n := p.Individuals
+	r := rng.New(rng.MRG32k3aStreams(p.Seed, 1)[0])
+	c := &Contacts{adj: make([][]int32, n)}
+
+	linked := func(a, b int) bool {
+		for _, x := range c.adj[a] {
+			if int(x) == b {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < n; i++ {
+		for k := 1; k <= p.Degree/2; k++ {
+			j := (i + k) % n
+			if r.RandFloat() < p.Rewire {
+				/* a few attempts, then the lattice contact is kept */
+				for try := 0; try < 8; try++ {
+					x := int(r.RandIntUniform(0, int32(n-1)))
+					if x != i && !linked(i, x) {
+						j = x
+						break
+					}
+				}
+			}
+			if !linked(i, j) {
+				c.adj[i] = append(c.adj[i], int32(j))
+				c.adj[j] = append(c.adj[j], int32(i))
+			}
+		}
+	}
+	for i := range c.adj {
+		a := c.adj[i]
+		sort.Slice(a, func(x, y int) bool { return a[x] < a[y] })
+	}
+
+	seen := make(map[int]bool)
+	for len(c.Initial) < p.Initial {
+		if i := int(r.RandIntUniform(0, int32(n-1))); !seen[i] {
+			seen[i] = true
+			c.Initial = append(c.Initial, i)
+		}
+	}
+	sort.Ints(c.Initial)
+	return c
+}
+
+/* the contacts of individual i */
+func (c *Contacts) Of(i int) []int32 {
+	return c.adj[i]
+}
+
+/* the number of contacts, counted once */
+func (c *Contacts) Edges() int {
+	m := 0
+	for _, a := range c.adj {
+		m += len(a)
+	}
+	return m / 2
+}
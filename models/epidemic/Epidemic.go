@@ -0,0 +1,335 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+/*
+ * an SEIR epidemic over a contact network: the individuals (the entities)
+ * are susceptible, exposed, infectious or recovered. An infectious
+ * individual infects each of its contacts after an exponential time with
+ * the transmission rate Beta, unless it recovers before; an infected
+ * individual is exposed for an exponential latent period, then infectious
+ * for an exponential infectious period. With Latent = 0 the model is SIR.
+ * The times are in minutes, the rates and the periods in days.
+ *
+ * The state of an individual is saved and restored by the kernel; the
+ * counts of the days are computed from the committed events only (see
+ * Commit), so they are never affected by the events that are rolled back,
+ * and a day is emitted (see OnDay) once all the LPs have committed it.
+ * An infection that reaches an individual that is not susceptible anymore is
+ * ignored, so the events of an individual with the same time can be
+ * executed in any order: the sequential and the parallel runs have the
+ * same counts.
+ */
+package epidemic
+
+import (
+	"fmt"
+	"github.com/jeffallen/go-warp/rng"
+	"github.com/jeffallen/go-warp/warp"
+	"sync"
+	"sync/atomic"
+)
+
+const DAY = 24 * 60 // minutes
+
+/* the kinds of the events (Info.Flag) */
+const (
+	INFECT  = iota + 1 // a contact transmits the infection
+	ONSET              // the end of the latent period
+	RECOVER            // the end of the infectious period
+)
+
+/* the states of an individual */
+const (
+	SUSCEPTIBLE = iota
+	EXPOSED
+	INFECTIOUS
+	RECOVERED
+)
+
+type Params struct {
+	Individuals int     `json:"individuals"`
+	Degree      int     `json:"degree"`     // contacts of an individual in the lattice, even
+	Rewire      float64 `json:"rewire"`     // probability that a contact is rewired to a random individual
+	Beta        float64 `json:"beta"`       // transmission rate of a contact, per day
+	Latent      float64 `json:"latent"`     // mean latent period, days; 0 means SIR
+	Infectious  float64 `json:"infectious"` // mean infectious period, days
+	Initial     int     `json:"initial"`    // individuals infected at the start
+	Days        int     `json:"days"`       // simulated time
+	Seed        int64   `json:"seed"`       // of the contact network and of the initial infections
+}
+
+/* an individual, implements warp.Entity and warp.Committer */
+type Individual struct {
+	State int8
+
+	/* the committed history, not saved: the times of the transitions, -1 if not happened */
+	Infected, Onset, Recovered warp.Time
+}
+
+/* the counts at the end of a day */
+type Day struct {
+	Day         int `json:"day"`
+	Susceptible int `json:"susceptible"`
+	Exposed     int `json:"exposed"`
+	Infectious  int `json:"infectious"`
+	Recovered   int `json:"recovered"`
+	New         int `json:"new"` // infections of the day
+}
+
+/* an invalid parameter, Field is its JSON name */
+type ParamError struct {
+	Field string
+	Msg   string
+}
+
+func (e *ParamError) Error() string {
+	return "EPIDEMIC: " + e.Field + ": " + e.Msg
+}
+
+func paramError(field, format string, a ...interface{}) error {
+	return &ParamError{field, fmt.Sprintf(format, a...)}
+}
+
+func DefaultParams() Params {
+	return Params{Individuals: 10000, Degree: 10, Rewire: 0.1, Beta: 0.08, Latent: 3, Infectious: 5,
+		Initial: 10, Days: 120, Seed: 1}
+}
+
+var (
+	params   Params
+	people   []Individual
+	contacts *Contacts
+
+	/*
+	 * if not nil, called with the counts of each day as soon as they are
+	 * committed, in the order of the days and by one LP at a time: it
+	 * must not block the simulation for long
+	 */
+	OnDay func(d Day)
+
+	/* the committed transitions of every day, added by the LPs */
+	infected, onsets, recoveries []atomic.Int32
+
+	dayLock sync.Mutex
+	days    []Day        // emitted, see emitDays
+	emitted atomic.Int32 // len(days)
+	total   Day          // the counts at the end of the last emitted day
+)
+
+func (p *Params) Validate() error {
+	if p.Individuals < 2 {
+		return paramError("individuals", "at least 2 individuals are needed, not %d", p.Individuals)
+	}
+	if p.Degree < 2 || p.Degree%2 != 0 || p.Degree >= p.Individuals {
+		return paramError("degree", "must be even and in [2, %d), not %d", p.Individuals, p.Degree)
+	}
+	if p.Rewire < 0 || p.Rewire > 1 {
+		return paramError("rewire", "must be in [0, 1], not %v", p.Rewire)
+	}
+	if p.Beta < 0 {
+		return paramError("beta", "must not be negative, not %v", p.Beta)
+	}
+	if p.Latent < 0 {
+		return paramError("latent", "must not be negative, not %v", p.Latent)
+	}
+	if p.Infectious <= 0 {
+		return paramError("infectious", "must be positive, not %v", p.Infectious)
+	}
+	if p.Initial < 1 || p.Initial > p.Individuals {
+		return paramError("initial", "must be in [1, %d], not %d", p.Individuals, p.Initial)
+	}
+	if p.Days < 1 || int64(p.Days)*DAY > 1<<31-1 {
+		return paramError("days", "must be in [1, %d], not %d", (1<<31-1)/DAY, p.Days)
+	}
+	return nil
+}
+
+/* builds the contact network and prepares the individuals of a new simulation */
+func Setup(p Params) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	params = p
+	contacts = NewContacts(p)
+	people = make([]Individual, p.Individuals)
+	for i := range people {
+		people[i] = Individual{State: SUSCEPTIBLE, Infected: -1, Onset: -1, Recovered: -1}
+	}
+	infected, onsets, recoveries = make([]atomic.Int32, p.Days), make([]atomic.Int32, p.Days), make([]atomic.Int32, p.Days)
+	days, total = nil, Day{Day: -1, Susceptible: p.Individuals}
+	emitted.Store(0)
+	return nil
+}
+
+/* the parameters of the current simulation */
+func Current() Params {
+	return params
+}
+
+/* the contact network of the current simulation */
+func CurrentContacts() *Contacts {
+	return contacts
+}
+
+/*
+ * runs the epidemic with the parameters p, the kernel options (LPs,
+ * Sequential, Context, Seed, Generator, Partition, Window) are taken from
+ * cfg. The contacts are mostly between close individuals, so the default
+ * block partition keeps most of the infections in the same LP
+ */
+func Run(cfg warp.Config, p Params) (*warp.Result, error) {
+	if err := Setup(p); err != nil {
+		return nil, err
+	}
+	cfg.EndTime = warp.Time(p.Days * DAY)
+	cfg.Entities = Entities()
+	res, err := warp.Run(cfg, InitLP)
+	emitDays(warp.CommittedTime()) // the last days, or those before the GVT of a cancelled run
+	return res, err
+}
+
+func Entities() []warp.Entity {
+	ents := make([]warp.Entity, len(people))
+	for i := range ents {
+		ents[i] = &people[i]
+	}
+	return ents
+}
+
+/* the initial infections of the individuals of the LP, in the first minute */
+func InitLP(l *warp.LocalData) error {
+	for _, i := range contacts.Initial {
+		if warp.EntityLP(i) == l.IndexLP {
+			l.NewEvent(warp.CreateEvent(0, 1, warp.Info{From: i, To: i, Flag: INFECT}))
+		}
+	}
+	return nil
+}
+
+/* the individuals, once the simulation is over */
+func People() []Individual {
+	return people
+}
+
+func (ind *Individual) Handle(ev *warp.Event, l *warp.LocalData) {
+	r := l.Rand()
+	self := ev.Type.To
+
+	switch ev.Type.Flag {
+	case INFECT:
+		if ind.State != SUSCEPTIBLE {
+			return
+		}
+		if params.Latent == 0 {
+			ind.infectious(self, ev.Time, r, l)
+			return
+		}
+		ind.State = EXPOSED
+		schedule(self, ev.Time, r.RandExponential(params.Latent), ONSET, l)
+	case ONSET:
+		ind.infectious(self, ev.Time, r, l)
+	case RECOVER:
+		ind.State = RECOVERED
+	default:
+		l.Fail(fmt.Errorf("unknown event kind %d for the individual %d", ev.Type.Flag, ev.Type.To))
+	}
+}
+
+/* the individual infects the contacts that it meets before its recovery */
+func (ind *Individual) infectious(self int, t warp.Time, r *rng.Rand, l *warp.LocalData) {
+	ind.State = INFECTIOUS
+	d := r.RandExponential(params.Infectious)
+	schedule(self, t, d, RECOVER, l)
+	if params.Beta == 0 {
+		return
+	}
+	for _, c := range contacts.Of(self) {
+		if x := r.RandExponential(1 / params.Beta); x < d {
+			schedule(int(c), t, x, INFECT, l)
+		}
+	}
+}
+
+/* an event d days after t, at least one minute later */
+func schedule(to int, t warp.Time, d float64, k int32, l *warp.LocalData) {
+	ev := warp.CreateEvent(0, t+1+warp.Time(d*DAY), warp.Info{Flag: k})
+	if err := warp.NoticeEntity(ev, to, l); err != nil {
+		l.Fail(fmt.Errorf("event not sent to the individual %d: %w", to, err))
+	}
+}
+
+func (ind *Individual) Save() interface{} {
+	return ind.State
+}
+
+func (ind *Individual) Restore(s interface{}) {
+	ind.State = s.(int8)
+}
+
+/*
+ * records the committed transitions: the first committed infection is the
+ * one that happened. The days before the event are over once all the LPs
+ * have committed them, see emitDays
+ */
+func (ind *Individual) Commit(ev *warp.Event) {
+	d := ev.Time / DAY
+	switch ev.Type.Flag {
+	case INFECT:
+		if ind.Infected < 0 {
+			ind.Infected = ev.Time
+			infected[d].Add(1)
+			if params.Latent == 0 {
+				ind.Onset = ev.Time
+				onsets[d].Add(1)
+			}
+		}
+	case ONSET:
+		ind.Onset = ev.Time
+		onsets[d].Add(1)
+	case RECOVER:
+		ind.Recovered = ev.Time
+		recoveries[d].Add(1)
+	}
+	emitDays(warp.CommittedTime())
+}
+
+/*
+ * the days that are over, once the counts of all the LPs have been
+ * committed: during the run as the GVT passes the end of the days, all of
+ * them once the simulation is over
+ */
+func Daily() []Day {
+	dayLock.Lock()
+	defer dayLock.Unlock()
+	return days
+}
+
+/* emits the days that end by the committed time t */
+func emitDays(t warp.Time) {
+	if d := int(emitted.Load()); d == params.Days || warp.Time((d+1)*DAY-1) > t {
+		return // without the lock, called for every committed event
+	}
+	dayLock.Lock()
+	defer dayLock.Unlock()
+
+	for d := len(days); d < params.Days && warp.Time((d+1)*DAY-1) <= t; d = len(days) {
+		inf, ons, rec := int(infected[d].Load()), int(onsets[d].Load()), int(recoveries[d].Load())
+		total = Day{Day: d, Susceptible: total.Susceptible - inf, Exposed: total.Exposed + inf - ons,
+			Infectious: total.Infectious + ons - rec, Recovered: total.Recovered + rec, New: inf}
+		days = append(days, total)
+		emitted.Store(int32(len(days)))
+		if OnDay != nil {
+			OnDay(total)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package epidemic
+
+import (
+	"fmt"
+	"github.com/jeffallen/go-warp/warp"
+	"github.com/jeffallen/go-warp/warp/warptest"
+	"testing"
+)
+
+func TestContacts(t *testing.T) {
+	p := DefaultParams()
+	p.Individuals, p.Rewire = 1000, 0.3
+	c := NewContacts(p)
+	if e := c.Edges(); e < p.Individuals*p.Degree/2*95/100 || e > p.Individuals*p.Degree/2 {
+		t.Fatalf("%d contacts, expected about %d", e, p.Individuals*p.Degree/2)
+	}
+	for i := 0; i < p.Individuals; i++ {
+		for k, j := range c.Of(i) {
+			if int(j) == i || k > 0 && c.Of(i)[k-1] >= j {
+				t.Fatalf("the contacts of %d are %v", i, c.Of(i))
+			}
+			found := false
+			for _, x := range c.Of(int(j)) {
+				found = found || int(x) == i
+			}
+			if !found {
+				t.Fatalf("%d is a contact of %d, but not the reverse", j, i)
+			}
+		}
+	}
+	if len(c.Initial) != p.Initial {
+		t.Fatalf("initial infections %v", c.Initial)
+	}
+}
+
+/* without transmission only the initial individuals are infected, and they recover */
+func TestNoSpread(t *testing.T) {
+	p := DefaultParams()
+	p.Individuals, p.Beta = 1000, 0
+	if _, err := Run(warp.Config{LPs: 1, Sequential: true, Seed: 1}, p); err != nil {
+		t.Fatal(err)
+	}
+	last := Daily()[p.Days-1]
+	if last.Recovered != p.Initial || last.Susceptible != p.Individuals-p.Initial {
+		t.Fatalf("%+v", last)
+	}
+}
+
+/*
+ * with and without the latent period the daily counts are conserved and
+ * the epidemic spreads. The rewired contacts send the infections to the
+ * other LPs, the parallel runs must commit the same first infection of
+ * every individual and end with the history and the days of the sequential
+ * run
+ */
+func TestRegression(t *testing.T) {
+	type state struct {
+		People []Individual
+		Daily  []Day
+	}
+	for _, latent := range []float64{3, 0} {
+		p := DefaultParams()
+		p.Individuals, p.Days, p.Latent = 4000, 60, latent
+
+		if !t.Run(fmt.Sprint("latent ", latent), func(t *testing.T) {
+			warptest.Compare(t, warp.Config{LPs: 4, Seed: 2, Window: 2 * DAY},
+				[]warp.Partitioner{nil, warp.RoundRobinPartition{LPs: 4}},
+				func(cfg warp.Config) (*warp.Result, error) { return Run(cfg, p) },
+				func() interface{} { return state{append([]Individual(nil), People()...), Daily()} })
+		}) {
+			continue
+		}
+
+		days := Daily()
+		for _, d := range days {
+			if d.Susceptible+d.Exposed+d.Infectious+d.Recovered != p.Individuals || latent == 0 && d.Exposed != 0 {
+				t.Fatalf("latent %v: %+v", latent, d)
+			}
+		}
+		if last := days[p.Days-1]; last.Recovered < p.Individuals/4 {
+			t.Fatalf("latent %v: the epidemic did not spread, %+v", latent, last)
+		}
+	}
+}
+
+/* the days are emitted in order while the LPs run, once all of them have committed the day */
+func TestOnDay(t *testing.T) {
+	p := DefaultParams()
+	p.Individuals, p.Days = 4000, 30
+	var got []Day
+	var during int
+	OnDay = func(d Day) {
+		if c := warp.CommittedTime(); c < warp.Time((d.Day+1)*DAY-1) {
+			t.Errorf("day %d emitted with the events up to %d committed", d.Day, c)
+		} else if c != warp.MAXTIME {
+			during++
+		}
+		got = append(got, d)
+	}
+	defer func() { OnDay = nil }()
+
+	if _, err := Run(warp.Config{LPs: 4, Seed: 1, Window: 2 * DAY}, p); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != p.Days || during == 0 {
+		t.Fatalf("%d days, %d of them during the run", len(got), during)
+	}
+	for d, day := range Daily() {
+		if got[d] != day || day.Day != d {
+			t.Fatalf("day %d emitted as %+v, Daily has %+v", d, got[d], day)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package pcs
+
+/*
+ * EXPECTED STATISTICS
+ *
+ * in the steady state every cell is an M/M/C/C queue: the new calls and
+ * the handed off ones arrive as Poisson processes and a call holds its
+ * channel for the minimum of two exponentials. The rate of the handoffs
+ * depends on the carried traffic, that depends on the blocking, so the
+ * blocking probability is the fixed point of the Erlang B formula (all
+ * the cells are equal, the handoffs into a cell balance the ones out of
+ * it).
+ */
+
+/* the blocking probability of C channels with an offered load of a Erlangs */
+func ErlangB(c int, a float64) float64 {
+	b := 1.0
+	for k := 1; k <= c; k++ {
+		b = a * b / (float64(k) + a*b)
+	}
+	return b
+}
+
+type Expectation struct {
+	Load     float64 // offered load of a cell, in Erlangs
+	Blocking float64 // probability that a call (new or handed off) finds no free channel
+	Handoffs float64 // handoffs per new call
+}
+
+/* the steady state of the network with the parameters p */
+func Expected(p Params) Expectation {
+	eps := float64(SLOTS) / 2 // the mean rounding of a delay, see at
+
+	lambda := 1 / (p.ArrivalMean + eps)
+	mu := 1/p.CallMean + 1/p.MoveMean
+	hold := 1/mu + eps
+	moving := (1 / p.MoveMean) / mu // fraction of the calls that move
+
+	var h, a, b float64
+	for i := 0; i < 1000; i++ {
+		a = (lambda + h) * hold
+		b = ErlangB(p.Channels, a)
+		next := a * (1 - b) / hold * moving
+		if next-h < 1e-12 && h-next < 1e-12 {
+			break
+		}
+		h = next
+	}
+	return Expectation{Load: a, Blocking: b, Handoffs: h / lambda}
+}
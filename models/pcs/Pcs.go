@@ -0,0 +1,308 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+/*
+ * PCS (Personal Communication Service), the cellular network model of
+ * Carothers, Fujimoto and Lin: a torus of Rows x Cols cells, each one with
+ * Channels radio channels. The calls arrive at every cell as a Poisson
+ * process; a call is blocked if the cell has no free channel, otherwise it
+ * lasts an exponential time and, while it lasts, the portable moves to one
+ * of the 4 neighbouring cells after an exponential time: the call is handed
+ * off to the new cell, and it is dropped if that cell has no free channel.
+ *
+ * The cells are the entities of the model and, unlike the PHOLD ones, they
+ * have a state (the free channels and the counters) that is saved and
+ * restored by the kernel, so the final statistics of a parallel run are
+ * equal to the sequential ones only if the rollbacks are correct.
+ *
+ * The events of a cell are executed in the same order by every kernel:
+ * the time of an event is rounded up to the slot of its kind (see SLOTS),
+ * so two events of a cell with the same time have the same kind and the
+ * same sender, and their order does not change the state of the cell.
+ */
+package pcs
+
+import (
+	"fmt"
+	"github.com/jeffallen/go-warp/rng"
+	"github.com/jeffallen/go-warp/warp"
+)
+
+/* the kinds of the events (Info.Flag), that are also the slots of their times */
+const (
+	ARRIVAL    = iota // a new call, the cell schedules the next one
+	COMPLETION        // the end of a call
+	MOVE              // the portable of a call leaves the cell
+	HANDOFF           // HANDOFF + direction: a call that arrives from a neighbour
+	SLOTS      = HANDOFF + 4
+)
+
+/* the directions of the moves */
+const (
+	NORTH = iota
+	EAST
+	SOUTH
+	WEST
+)
+
+type Params struct {
+	Rows        int       `json:"rows"`         // of the torus of cells
+	Cols        int       `json:"cols"`         // of the torus of cells
+	Channels    int       `json:"channels"`     // radio channels of every cell
+	ArrivalMean float64   `json:"arrival_mean"` // mean time between two new calls in a cell
+	CallMean    float64   `json:"call_mean"`    // mean duration of a call
+	MoveMean    float64   `json:"move_mean"`    // mean time spent by a portable in a cell
+	EndTime     warp.Time `json:"end_time"`     // simulated time
+}
+
+/* the counters of a cell, or of the whole network */
+type Stats struct {
+	Attempts        int `json:"attempts"`         // new calls
+	Blocked         int `json:"blocked"`          // new calls that found no free channel
+	Completed       int `json:"completed"`        // calls ended in the cell
+	Moves           int `json:"moves"`            // calls handed off to a neighbour
+	Handoffs        int `json:"handoffs"`         // calls handed off by a neighbour
+	HandoffsBlocked int `json:"handoffs_blocked"` // handed off calls dropped for lack of channels
+}
+
+/* a cell, implements warp.Entity */
+type Cell struct {
+	Free int // free channels
+	Stats
+}
+
+/* an invalid parameter, Field is its JSON name */
+type ParamError struct {
+	Field string
+	Msg   string
+}
+
+func (e *ParamError) Error() string {
+	return "PCS: " + e.Field + ": " + e.Msg
+}
+
+func paramError(field, format string, a ...interface{}) error {
+	return &ParamError{field, fmt.Sprintf(format, a...)}
+}
+
+/* the times are in ticks: the slots of the event kinds are a small part of the means */
+func DefaultParams() Params {
+	return Params{Rows: 8, Cols: 8, Channels: 10, ArrivalMean: 100, CallMean: 600, MoveMean: 1200,
+		EndTime: 100000}
+}
+
+var (
+	params Params
+	cells  []Cell
+)
+
+func (p *Params) Validate() error {
+	if p.Rows < 1 {
+		return paramError("rows", "must be positive, not %d", p.Rows)
+	}
+	if p.Cols < 1 {
+		return paramError("cols", "must be positive, not %d", p.Cols)
+	}
+	if p.Channels < 1 {
+		return paramError("channels", "must be positive, not %d", p.Channels)
+	}
+	if p.ArrivalMean <= 0 {
+		return paramError("arrival_mean", "must be positive, not %v", p.ArrivalMean)
+	}
+	if p.CallMean <= 0 {
+		return paramError("call_mean", "must be positive, not %v", p.CallMean)
+	}
+	if p.MoveMean <= 0 {
+		return paramError("move_mean", "must be positive, not %v", p.MoveMean)
+	}
+	if p.EndTime <= 0 {
+		return paramError("end_time", "must be positive, not %d", p.EndTime)
+	}
+	return nil
+}
+
+/* prepares the cells of a new simulation, all the channels are free */
+func Setup(p Params) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	params = p
+	cells = make([]Cell, p.Rows*p.Cols)
+	for i := range cells {
+		cells[i].Free = p.Channels
+	}
+	return nil
+}
+
+/* the parameters of the current simulation */
+func Current() Params {
+	return params
+}
+
+/*
+ * runs PCS with the parameters p, the kernel options (LPs, Sequential,
+ * Context, Seed, Generator, migration) are taken from cfg
+ */
+func Run(cfg warp.Config, p Params) (*warp.Result, error) {
+	if err := Setup(p); err != nil {
+		return nil, err
+	}
+	cfg.EndTime = p.EndTime
+	cfg.Entities = Entities()
+	return warp.Run(cfg, InitLP)
+}
+
+func Entities() []warp.Entity {
+	ents := make([]warp.Entity, len(cells))
+	for i := range ents {
+		ents[i] = &cells[i]
+	}
+	return ents
+}
+
+/* each LP schedules the first call of the cells it owns, drawn from their streams */
+func InitLP(l *warp.LocalData) error {
+	for c := range cells {
+		if warp.EntityLP(c) != l.IndexLP {
+			continue
+		}
+		r := warp.EntityStream(c)
+		ev := warp.CreateEvent(0, at(0, r.RandExponential(params.ArrivalMean), ARRIVAL), warp.Info{From: c, To: c, Flag: ARRIVAL})
+		l.NewEvent(ev)
+	}
+	return nil
+}
+
+/* the state of the cells, once the simulation is over */
+func Cells() []Cell {
+	return cells
+}
+
+/* the counters of the whole network */
+func Totals() Stats {
+	var s Stats
+	for i := range cells {
+		s.add(&cells[i].Stats)
+	}
+	return s
+}
+
+func (s *Stats) add(t *Stats) {
+	s.Attempts += t.Attempts
+	s.Blocked += t.Blocked
+	s.Completed += t.Completed
+	s.Moves += t.Moves
+	s.Handoffs += t.Handoffs
+	s.HandoffsBlocked += t.HandoffsBlocked
+}
+
+/* fraction of the new calls that have been blocked */
+func (s *Stats) Blocking() float64 {
+	if s.Attempts == 0 {
+		return 0
+	}
+	return float64(s.Blocked) / float64(s.Attempts)
+}
+
+/* fraction of the handed off calls that have been dropped */
+func (s *Stats) Dropping() float64 {
+	if s.Handoffs+s.HandoffsBlocked == 0 {
+		return 0
+	}
+	return float64(s.HandoffsBlocked) / float64(s.Handoffs+s.HandoffsBlocked)
+}
+
+func (c *Cell) Handle(ev *warp.Event, l *warp.LocalData) {
+	r := l.Rand()
+	self := ev.Type.To
+
+	switch k := ev.Type.Flag; {
+	case k == ARRIVAL:
+		next := warp.CreateEvent(0, at(ev.Time, r.RandExponential(params.ArrivalMean), ARRIVAL), warp.Info{Flag: ARRIVAL})
+		notice(next, self, l)
+		c.Attempts++
+		if c.Free == 0 {
+			c.Blocked++
+			return
+		}
+		c.Free--
+		c.startCall(ev.Time, self, r, l)
+	case k == COMPLETION:
+		c.Free++
+		c.Completed++
+	case k == MOVE:
+		c.Free++
+		c.Moves++
+		dir := int32(r.RandIntUniform(0, 3))
+		ho := warp.CreateEvent(0, at(ev.Time, 0, HANDOFF+dir), warp.Info{Flag: HANDOFF + dir})
+		notice(ho, neighbour(self, dir), l)
+	case k >= HANDOFF && k < SLOTS:
+		if c.Free == 0 {
+			c.HandoffsBlocked++
+			return
+		}
+		c.Free--
+		c.Handoffs++
+		c.startCall(ev.Time, self, r, l) // the durations are exponential, the rest of the call is drawn again
+	default:
+		l.Fail(fmt.Errorf("unknown event kind %d for the cell %d", k, ev.Type.To))
+	}
+}
+
+/* a call that has got a channel either ends in the cell or moves to a neighbour */
+func (c *Cell) startCall(t warp.Time, self int, r *rng.Rand, l *warp.LocalData) {
+	d := r.RandExponential(params.CallMean)
+	m := r.RandExponential(params.MoveMean)
+	if d <= m {
+		notice(warp.CreateEvent(0, at(t, d, COMPLETION), warp.Info{Flag: COMPLETION}), self, l)
+	} else {
+		notice(warp.CreateEvent(0, at(t, m, MOVE), warp.Info{Flag: MOVE}), self, l)
+	}
+}
+
+/* an event refused by the kernel stops the simulation */
+func notice(ev *warp.Event, to int, l *warp.LocalData) {
+	if err := warp.NoticeEntity(ev, to, l); err != nil {
+		l.Fail(fmt.Errorf("event not sent to the cell %d: %w", to, err))
+	}
+}
+
+func (c *Cell) Save() interface{} {
+	return *c
+}
+
+func (c *Cell) Restore(s interface{}) {
+	*c = s.(Cell)
+}
+
+/* the first time after t + d (at least t + 1) in the slot of kind k */
+func at(t warp.Time, d float64, k int32) warp.Time {
+	base := t + 1 + warp.Time(d)
+	return base + (warp.Time(k)-base%SLOTS+SLOTS)%SLOTS
+}
+
+/* the neighbour of cell c in direction dir, on the torus */
+func neighbour(c int, dir int32) int {
+	row, col := c/params.Cols, c%params.Cols
+	switch dir {
+	case NORTH:
+		row = (row + params.Rows - 1) % params.Rows
+	case EAST:
+		col = (col + 1) % params.Cols
+	case SOUTH:
+		row = (row + 1) % params.Rows
+	case WEST:
+		col = (col + params.Cols - 1) % params.Cols
+	}
+	return row*params.Cols + col
+}
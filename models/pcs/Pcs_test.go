@@ -0,0 +1,98 @@
+package pcs
+
+import (
+	"github.com/jeffallen/go-warp/warp"
+	"github.com/jeffallen/go-warp/warp/warptest"
+	"math"
+	"testing"
+)
+
+func TestErlangB(t *testing.T) {
+	for _, c := range []struct {
+		c    int
+		a, b float64
+	}{{1, 1, 0.5}, {2, 1, 0.2}, {10, 5, 0.018385}} {
+		if b := ErlangB(c.c, c.a); math.Abs(b-c.b) > 1e-6 {
+			t.Fatalf("ErlangB(%d, %v) = %v, want %v", c.c, c.a, b, c.b)
+		}
+	}
+}
+
+/* the events of a cell never share their time with an event of another kind or sender */
+func TestSlots(t *testing.T) {
+	for k := int32(0); k < SLOTS; k++ {
+		for now := warp.Time(0); now < 20; now++ {
+			for _, d := range []float64{0, 0.5, 3, 17.9} {
+				if s := at(now, d, k); s <= now || s%SLOTS != warp.Time(k) || float64(s) < float64(now)+d {
+					t.Fatalf("at(%d, %v, %d) = %d", now, d, k, s)
+				}
+			}
+		}
+	}
+	params = Params{Rows: 3, Cols: 4}
+	for c := 0; c < 12; c++ {
+		for dir := int32(NORTH); dir <= WEST; dir++ {
+			if back := neighbour(neighbour(c, dir), (dir+2)%4); back != c {
+				t.Fatalf("cell %d, direction %d: the way back leads to %d", c, dir, back)
+			}
+		}
+	}
+}
+
+/*
+ * a call holds a channel of its cell across the rollbacks of the arrival,
+ * the completion and the handoffs: every partition, including the hashed
+ * one that splits the neighbours of most cells, ends with the counters and
+ * the free channels of the sequential run. The events of a cell are sparse
+ * in time, without a window the LPs run far ahead and the test is slow
+ */
+func TestRollback(t *testing.T) {
+	p := DefaultParams()
+	p.Rows, p.Cols, p.Channels, p.EndTime = 6, 6, 4, 20000
+
+	warptest.Compare(t, warp.Config{LPs: 4, Seed: 7, Window: 2000},
+		[]warp.Partitioner{nil, warp.HashPartition{LPs: 4}, warp.RoundRobinPartition{LPs: 4}},
+		func(cfg warp.Config) (*warp.Result, error) { return Run(cfg, p) },
+		func() interface{} { return append([]Cell(nil), Cells()...) })
+	if Totals().Blocked == 0 || Totals().Moves == 0 {
+		t.Fatalf("no blocked calls or no handoffs: %+v", Totals())
+	}
+}
+
+/* a long run has the blocking probability of the Erlang fixed point */
+func TestExpected(t *testing.T) {
+	p := DefaultParams()
+	p.EndTime = 400000
+	if _, err := Run(warp.Config{LPs: 1, Sequential: true, Seed: 1}, p); err != nil {
+		t.Fatal(err)
+	}
+	s, e := Totals(), Expected(p)
+	if got := s.Blocking(); math.Abs(got-e.Blocking) > 0.2*e.Blocking {
+		t.Fatalf("blocking %v, expected %v (load %v)", got, e.Blocking, e.Load)
+	}
+	if got := float64(s.Handoffs+s.HandoffsBlocked) / float64(s.Attempts); math.Abs(got-e.Handoffs) > 0.05*e.Handoffs {
+		t.Fatalf("%v handoffs per call, expected %v", got, e.Handoffs)
+	}
+	t.Logf("%+v: blocking %v, dropping %v, expected %+v", s, s.Blocking(), s.Dropping(), e)
+}
+
+/* an event of an unknown kind stops the simulation with an error */
+func TestUnknownKind(t *testing.T) {
+	p := DefaultParams()
+	p.EndTime = 1000
+	if err := Setup(p); err != nil {
+		t.Fatal(err)
+	}
+	for _, seq := range []bool{true, false} {
+		cfg := warp.Config{LPs: 2, EndTime: p.EndTime, Entities: Entities(), Sequential: seq}
+		_, err := warp.Run(cfg, func(l *warp.LocalData) error {
+			if l.IndexLP == 0 {
+				l.NewEvent(warp.CreateEvent(0, at(0, 1, SLOTS-1)+1, warp.Info{Flag: SLOTS}))
+			}
+			return InitLP(l)
+		})
+		if err == nil {
+			t.Fatalf("sequential %v: the unknown event has been executed", seq)
+		}
+	}
+}
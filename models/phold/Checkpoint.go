@@ -0,0 +1,51 @@
+/*
+	Go-based implementation of the PHOLD synthetic benchmark
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package phold
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/jeffallen/go-warp/lcg16807"
+	"github.com/jeffallen/go-warp/warp"
+)
+
+// the PHOLD LPs are stateless, the model state is shared by all of them
+type pholdState struct {
+	Params Params
+	Rng    lcg16807.RNG
+}
+
+/* implements warp.Checkpointer */
+type Checkpointer struct{}
+
+func (Checkpointer) SaveModel() ([]byte, error) {
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(pholdState{params, *randGen})
+	return b.Bytes(), err
+}
+
+func (Checkpointer) RestoreModel(b []byte) error {
+	var s pholdState
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&s); err != nil {
+		return err
+	}
+	params = s.Params
+	randGen = &s.Rng
+	setupVariants()
+	return nil
+}
+
+func (Checkpointer) SaveLP(l *warp.LocalData) ([]byte, error) { return nil, nil }
+
+func (Checkpointer) RestoreLP(l *warp.LocalData, b []byte) error { return nil }
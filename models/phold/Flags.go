@@ -0,0 +1,162 @@
+/*
+	Go-based implementation of the PHOLD synthetic benchmark
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package phold
+
+/*
+ * THE COMMAND LINE FLAGS
+ *
+ * every parameter has a flag, defined once in Flags: the PHOLD command
+ * maps it to the path model.FIELD of its configuration, pholdbench
+ * registers the flags with RegisterFlags. The values are parsed by Set,
+ * the phases are written as START:FPOPS,...
+ */
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/* the flag Name sets the parameter with the JSON name Field */
+type Flag struct {
+	Name, Field, Usage string
+}
+
+var Flags = []Flag{
+	{"entities", "entities", "number of simulated entities"},
+	{"density", "density", "initial events per entity"},
+	{"endtime", "end_time", "simulated time"},
+	{"fpops", "fpops", "synthetic workload, FP operations of every event"},
+	{"seed", "seed", "seed of the initial events"},
+	{"rng", "generator", "generator of the random streams: mrg32k3a, pcg, xoshiro or lcg16807"},
+	{"remote", "remote", "fraction of the events sent to another group of entities, negative means any entity"},
+	{"groups", "groups", "number of groups of entities for -remote, 0 means one per LP"},
+	{"lookahead", "lookahead", "minimum delay of an event"},
+	{"mean", "mean", "mean of the exponential part of the delay"},
+	{"hotspots", "hot_spots", "number of hot spot entities"},
+	{"hot", "hot", "fraction of the events sent to the hot spots"},
+	{"zipf", "zipf", "exponent of the Zipf distribution of the destinations, 0 means none"},
+	{"phases", "phases", "workload phases as START:FPOPS,... (e.g. 0:1000,500:20000)"},
+	{"payload", "payload", "bytes of data carried by every event"},
+}
+
+/* registers the flags of the parameters of p but the ones in except, the values of p are the defaults */
+func RegisterFlags(fs *flag.FlagSet, p *Params, except ...string) {
+Loop:
+	for _, f := range Flags {
+		for _, x := range except {
+			if f.Name == x {
+				continue Loop
+			}
+		}
+		fs.Var(&paramValue{p, f.Field}, f.Name, f.Usage)
+	}
+}
+
+type paramValue struct {
+	p     *Params
+	field string
+}
+
+func (v *paramValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return v.p.Get(v.field)
+}
+
+func (v *paramValue) Set(s string) error {
+	return v.p.Set(v.field, s)
+}
+
+/* the parameter with the JSON name field, the zero Value if there is none */
+func (p *Params) field(name string) reflect.Value {
+	v := reflect.ValueOf(p).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0] == name {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+/* the value of the parameter field, as written in the flags */
+func (p *Params) Get(field string) string {
+	f := p.field(field)
+	if !f.IsValid() {
+		return ""
+	}
+	if ph, ok := f.Interface().([]Phase); ok {
+		var s []string
+		for _, x := range ph {
+			s = append(s, fmt.Sprintf("%d:%d", x.Start, x.FPOps))
+		}
+		return strings.Join(s, ",")
+	}
+	return fmt.Sprint(f.Interface())
+}
+
+/* sets the parameter field from its representation in the flags */
+func (p *Params) Set(field, s string) error {
+	f := p.field(field)
+	switch {
+	case !f.IsValid():
+		return paramError(field, "unknown parameter")
+	case f.Type() == reflect.TypeOf([]Phase(nil)):
+		ph, err := ParsePhases(s)
+		if err != nil {
+			return paramError(field, "%v", err)
+		}
+		f.Set(reflect.ValueOf(ph))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return paramError(field, "expected an integer, not %q", s)
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return paramError(field, "expected a number, not %q", s)
+		}
+		f.SetFloat(x)
+	default:
+		return paramError(field, "cannot be set")
+	}
+	return nil
+}
+
+/* parses the phases in the START:FPOPS,... format of the flags */
+func ParsePhases(s string) ([]Phase, error) {
+	var v []Phase
+	if s == "" {
+		return nil, nil
+	}
+	for _, f := range strings.Split(s, ",") {
+		var ph Phase
+		if _, err := fmt.Sscanf(f, "%d:%d", &ph.Start, &ph.FPOps); err != nil {
+			return nil, fmt.Errorf("invalid phase %q: %v", f, err)
+		}
+		v = append(v, ph)
+	}
+	return v, nil
+}
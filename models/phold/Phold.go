@@ -0,0 +1,224 @@
+/*
+	Go-based implementation of the PHOLD synthetic benchmark
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+/*
+ * the PHOLD model as a package, so that it can be run in-process by the
+ * PHOLD command and by the benchmark runner. As the kernel, the model
+ * keeps its state in package variables: one simulation at a time
+ */
+package phold
+
+import (
+	"fmt"
+	"github.com/jeffallen/go-warp/lcg16807"
+	"github.com/jeffallen/go-warp/rng"
+	"github.com/jeffallen/go-warp/warp"
+	"runtime"
+)
+
+type Params struct {
+	Entities  int       `json:"entities"`  // number of simulated entities
+	Density   float64   `json:"density"`   // initial events per entity
+	EndTime   warp.Time `json:"end_time"`  // simulated time
+	FPOps     int       `json:"fpops"`     // synthetic workload, floating point operations of every event
+	Seed      int64     `json:"seed"`      // of the initial events
+	Generator string    `json:"generator"` // of the random streams of the entities, see Generators
+
+	/* the variants, see Variants.go */
+	Remote    float64   `json:"remote"`    // fraction of the events sent to another group, negative means any entity
+	Groups    int       `json:"groups"`    // the entities are split in Groups blocks, 0 means one per LP
+	Lookahead warp.Time `json:"lookahead"` // minimum delay of an event, at least 1
+	Mean      float64   `json:"mean"`      // of the exponential part of the delay
+	HotSpots  int       `json:"hot_spots"` // the entities 0..HotSpots-1 are hot spots
+	Hot       float64   `json:"hot"`       // fraction of the events sent to a hot spot
+	Zipf      float64   `json:"zipf"`      // if > 0 the destinations have a Zipf distribution with this exponent
+	Phases    []Phase   `json:"phases"`    // the workload changes over time
+	Payload   int       `json:"payload"`   // bytes of data carried by every event
+}
+
+/* an invalid parameter, Field is its JSON name */
+type ParamError struct {
+	Field string
+	Msg   string
+}
+
+func (e *ParamError) Error() string {
+	return "PHOLD: " + e.Field + ": " + e.Msg
+}
+
+func paramError(field, format string, a ...interface{}) error {
+	return &ParamError{field, fmt.Sprintf(format, a...)}
+}
+
+/* the original PHOLD */
+func DefaultParams() Params {
+	return Params{Entities: 1000, Density: 0.5, EndTime: 1000, FPOps: 10000, Seed: 1, Generator: "mrg32k3a",
+		Remote: -1, Lookahead: 1, Mean: lcg16807.LAMBDA}
+}
+
+/* the module of lcg16807, a seed multiple of it gives a null stream */
+const seedModule = 1<<31 - 1
+
+var Generators = map[string]rng.Source{
+	"mrg32k3a": rng.MRG32k3aStreams,
+	"pcg":      rng.PCGStreams,
+	"xoshiro":  rng.XoshiroStreams,
+	"lcg16807": lcg16807.Streams,
+}
+
+var (
+	params   Params
+	n_events int
+	randGen  *lcg16807.RNG // draws the initial events, the entities use their own streams
+
+	initEv []warp.Event
+)
+
+func (p *Params) Validate() error {
+	if p.Entities < 2 {
+		return paramError("entities", "at least 2 entities are needed, not %d", p.Entities)
+	}
+	if p.Density <= 0 {
+		return paramError("density", "must be positive, not %v", p.Density)
+	}
+	if p.EndTime <= 0 {
+		return paramError("end_time", "must be positive, not %d", p.EndTime)
+	}
+	if p.FPOps < 0 {
+		return paramError("fpops", "must not be negative, not %d", p.FPOps)
+	}
+	if p.Seed%seedModule == 0 {
+		return paramError("seed", "must not be a multiple of %d, not %d", seedModule, p.Seed)
+	}
+	if p.Generator == "" {
+		p.Generator = "mrg32k3a"
+	}
+	if Generators[p.Generator] == nil {
+		return paramError("generator", "unknown random number generator %q", p.Generator)
+	}
+	return p.validateVariants()
+}
+
+/* generates the initial events of a new simulation */
+func Setup(p Params) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	params = p
+	n_events = int(float64(p.Entities) * p.Density)
+	setupVariants()
+	randGen = lcg16807.RandInit(p.Seed)
+
+	initEv = make([]warp.Event, n_events)
+
+	for i := 0; i < n_events; i++ {
+		e := generateEvent(nil, rng.New(randGen))
+		initEv[i] = *e
+	}
+	return nil
+}
+
+/* the parameters of the current simulation */
+func Current() Params {
+	return params
+}
+
+/*
+ * runs PHOLD with the parameters p, the kernel options (LPs, Sequential,
+ * Context, migration) are taken from cfg
+ */
+func Run(cfg warp.Config, p Params) (*warp.Result, error) {
+	if p.Groups == 0 {
+		p.Groups = cfg.LPs
+		if p.Groups == 0 {
+			p.Groups = runtime.NumCPU() // as warp.Run
+		}
+	}
+	if err := Setup(p); err != nil {
+		return nil, err
+	}
+	cfg.EndTime = p.EndTime
+	cfg.Entities = Entities()
+	if cfg.Seed == 0 {
+		cfg.Seed = int64(p.Entities)
+	}
+	cfg.Generator = Source()
+	return warp.Run(cfg, InitLP)
+}
+
+/* the generator of the random streams of the entities */
+func Source() rng.Source {
+	return Generators[params.Generator]
+}
+
+// each event in the system is generated in this function, drawing from r
+func generateEvent(oldev *warp.Event, r *rng.Rand) *warp.Event {
+	var mitt int
+	var dest int
+	var t warp.Time
+
+	if oldev == nil {
+		mitt = int(r.RandIntUniform(0, int32(params.Entities-1)))
+		t = 0 // basetime
+	} else {
+		mitt = oldev.Type.To
+		t = oldev.Time // basetime
+	}
+
+	dest = destination(mitt, r)
+	t += delay(r)
+
+	e := warp.CreateEvent(0, t, warp.Info{From: mitt, To: dest, Data: payload(t)}) // the Id is assigned by the kernel
+	return e
+}
+
+// each LP gets the events, generated at start up, of the entities it owns
+func InitLP(l *warp.LocalData) error {
+	for i := 0; i < n_events; i++ {
+		if warp.EntityLP(initEv[i].Type.To) == l.IndexLP {
+			ev := initEv[i]
+			l.NewEvent(&ev)
+		}
+	}
+	return nil
+}
+
+// the PHOLD entities have no state, they all execute ProcessEvent
+func Entities() []warp.Entity {
+	ents := make([]warp.Entity, params.Entities)
+	for i := range ents {
+		ents[i] = warp.EntityFunc(ProcessEvent)
+	}
+	return ents
+}
+
+func ProcessEvent(ev *warp.Event, l *warp.LocalData) {
+	readPayload(ev.Type.Data)
+	newev := generateEvent(ev, l.Rand()) // the stream of the entity, rolled back with the event
+	if err := warp.NoticeEntity(newev, newev.Type.To, l); err != nil {
+		l.Fail(fmt.Errorf("event not sent to the entity %d: %w", newev.Type.To, err))
+		return
+	}
+	compute(workload(ev.Time))
+}
+
+func compute(fpops int) float64 {
+	var z, x float64
+	z = 2
+	x = 0.5
+
+	for i := 0; i < fpops/5; i++ {
+		x = 0.5 * x * (3 - z*x*x)
+	}
+	return x
+}
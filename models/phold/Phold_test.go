@@ -0,0 +1,124 @@
+package phold
+
+import (
+	"flag"
+	"github.com/jeffallen/go-warp/rng"
+	"github.com/jeffallen/go-warp/warp"
+	"reflect"
+	"testing"
+)
+
+func TestGroups(t *testing.T) {
+	params = Params{Entities: 10, Groups: 3}
+	b := warp.BlockPartition{Entities: 10, LPs: 3}
+	for e := 0; e < 10; e++ {
+		lo, hi := group(e)
+		for f := 0; f < 10; f++ {
+			if (f >= lo && f < hi) != (b.LP(f) == b.LP(e)) {
+				t.Fatalf("entity %d: group [%d, %d) and entity %d of LP %d", e, lo, hi, f, b.LP(f))
+			}
+		}
+	}
+}
+
+func TestRemote(t *testing.T) {
+	r := rng.New(rng.MRG32k3aStreams(1, 1)[0])
+	for _, remote := range []float64{0, 1} {
+		params = DefaultParams()
+		params.Entities, params.Groups, params.Remote = 100, 7, remote
+		for i := 0; i < 1000; i++ {
+			e := i % 100
+			d := destination(e, r)
+			lo, hi := group(e)
+			if d == e || d < 0 || d >= 100 || (d >= lo && d < hi) != (remote == 0) {
+				t.Fatalf("remote %v: event from %d (group [%d, %d)) to %d", remote, e, lo, hi, d)
+			}
+		}
+		for i := 0; i < 1000; i++ {
+			if ev := generateEvent(nil, r); ev.Type.From < 0 || ev.Type.From >= 100 || ev.Type.To < 0 || ev.Type.To >= 100 {
+				t.Fatalf("remote %v: initial event from %d to %d", remote, ev.Type.From, ev.Type.To)
+			}
+		}
+	}
+}
+
+/* the variants do not depend on the kernel, the parallel runs commit the events of the sequential one */
+func TestVariants(t *testing.T) {
+	variants := []Params{
+		{Remote: 0.2, Lookahead: 3},
+		{Remote: -1, Lookahead: 1, Hot: 0.3, HotSpots: 3, Payload: 64},
+		{Remote: -1, Lookahead: 1, Zipf: 1.1, Phases: []Phase{{0, 0}, {50, 100}}},
+	}
+	for i, v := range variants {
+		p := DefaultParams()
+		p.Entities, p.EndTime, p.FPOps, p.Groups, p.Seed = 64, 100, 0, 4, 1
+		p.Remote, p.Lookahead, p.Hot, p.HotSpots, p.Zipf, p.Phases, p.Payload =
+			v.Remote, v.Lookahead, v.Hot, v.HotSpots, v.Zipf, v.Phases, v.Payload
+
+		seq, err := Run(warp.Config{LPs: 4, Sequential: true}, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		par, err := Run(warp.Config{LPs: 4}, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq.Stats.Committed != par.Stats.Committed {
+			t.Fatalf("variant %d: %d sequential and %d parallel events", i, seq.Stats.Committed, par.Stats.Committed)
+		}
+	}
+}
+
+/* the defaults are a complete configuration, a null seed is refused instead of giving a null stream */
+func TestDefaults(t *testing.T) {
+	p := DefaultParams()
+	p.EndTime = 100 // a shorter run, with the default model
+	res, err := Run(warp.Config{LPs: 2}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Stats.Committed == 0 {
+		t.Fatal("no event committed with the default parameters")
+	}
+
+	p.Seed = 0
+	if _, err := Run(warp.Config{LPs: 2}, p); err == nil {
+		t.Fatal("seed 0 accepted")
+	} else if pe, ok := err.(*ParamError); !ok || pe.Field != "seed" {
+		t.Fatalf("seed 0: %v", err)
+	}
+}
+
+/* every flag sets its parameter, and the value printed as default is parsed back */
+func TestFlags(t *testing.T) {
+	p := DefaultParams()
+	fs := flag.NewFlagSet("phold", flag.ContinueOnError)
+	RegisterFlags(fs, &p, "seed")
+	err := fs.Parse([]string{"-entities", "64", "-endtime", "50", "-remote", "0.25", "-rng", "pcg", "-phases", "0:10,20:30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Entities != 64 || p.EndTime != 50 || p.Remote != 0.25 || p.Generator != "pcg" ||
+		!reflect.DeepEqual(p.Phases, []Phase{{0, 10}, {20, 30}}) {
+		t.Fatalf("wrong parameters %+v", p)
+	}
+	if fs.Lookup("seed") != nil {
+		t.Fatal("the excluded flag -seed has been registered")
+	}
+
+	for _, f := range Flags {
+		s := p.Get(f.Field)
+		q := DefaultParams()
+		if err := q.Set(f.Field, s); err != nil || q.Get(f.Field) != s {
+			t.Fatalf("-%s: %q read back as %q (%v)", f.Name, s, q.Get(f.Field), err)
+		}
+	}
+	if err, ok := p.Set("fpops", "many").(*ParamError); !ok || err.Field != "fpops" {
+		t.Fatalf("-fpops many: %v", err)
+	}
+
+	p.Groups, p.Phases = 4, []Phase{{0, 10}, {20, -1}}
+	if err, ok := p.Validate().(*ParamError); !ok || err.Field != "phases[1].fpops" {
+		t.Fatalf("negative FP operations of a phase: %v", err)
+	}
+}
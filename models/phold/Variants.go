@@ -0,0 +1,183 @@
+/*
+	Go-based implementation of the PHOLD synthetic benchmark
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package phold
+
+/*
+ * THE PHOLD VARIANTS
+ *
+ * the destination of an event is:
+ *  - with probability Hot, one of the HotSpots entities (unless it is
+ *    the sender itself)
+ *  - otherwise, if Zipf > 0, an entity drawn from a Zipf distribution
+ *  - otherwise, if Remote >= 0, an entity of another group with
+ *    probability Remote, or one of the group of the sender. The groups
+ *    are the blocks of warp.BlockPartition, the initial placement of the
+ *    entities: with Groups LPs Remote is the fraction of the events that
+ *    are sent to another LP, until the entities are migrated
+ *  - otherwise any other entity, as in the original PHOLD
+ *
+ * the delay of an event is Lookahead plus an exponential with mean Mean,
+ * the FP operations of an event depend on its time through Phases and
+ * every event carries Payload bytes of data, that are read by the receiver
+ */
+
+import (
+	"fmt"
+	"github.com/jeffallen/go-warp/rng"
+	"github.com/jeffallen/go-warp/warp"
+)
+
+/* from time Start to the next phase every event executes FPOps FP operations */
+type Phase struct {
+	Start warp.Time `json:"start"`
+	FPOps int       `json:"fpops"`
+}
+
+var zipf *rng.Empirical // the distribution of the destinations if Zipf > 0
+
+func (p *Params) validateVariants() error {
+	if p.Remote > 1 {
+		return paramError("remote", "must be at most 1, not %v", p.Remote)
+	}
+	if p.Remote >= 0 && (p.Groups < 1 || p.Groups > p.Entities) {
+		return paramError("groups", "%d groups for %d entities", p.Groups, p.Entities)
+	}
+	if p.Lookahead < 1 {
+		return paramError("lookahead", "the minimum delay is 1, not %d", p.Lookahead)
+	}
+	if p.Mean < 0 {
+		return paramError("mean", "must not be negative, not %v", p.Mean)
+	}
+	if p.HotSpots < 0 || p.HotSpots > p.Entities {
+		return paramError("hot_spots", "%d hot spots for %d entities", p.HotSpots, p.Entities)
+	}
+	if p.Hot < 0 || p.Hot > 1 || (p.Hot > 0 && p.HotSpots < 1) {
+		return paramError("hot", "invalid fraction %v of the events to %d hot spots", p.Hot, p.HotSpots)
+	}
+	if p.Zipf < 0 || (p.Zipf > 0 && p.Remote >= 0) {
+		return paramError("zipf", "invalid exponent %v (it excludes the remote fraction)", p.Zipf)
+	}
+	for i, ph := range p.Phases {
+		if ph.FPOps < 0 {
+			return paramError(fmt.Sprintf("phases[%d].fpops", i), "must not be negative, not %d", ph.FPOps)
+		}
+		if i > 0 && ph.Start <= p.Phases[i-1].Start {
+			return paramError(fmt.Sprintf("phases[%d]", i), "the phases must have increasing start times")
+		}
+	}
+	if p.Payload < 0 {
+		return paramError("payload", "must not be negative, not %d", p.Payload)
+	}
+	return nil
+}
+
+func setupVariants() {
+	zipf = nil
+	if params.Zipf > 0 {
+		zipf = rng.NewZipf(params.Entities, params.Zipf)
+	}
+}
+
+/* the entities from lo to hi-1 are in the same group as e */
+func group(e int) (lo, hi int) {
+	n, g := params.Entities, params.Groups
+	d, m := n/g, n%g
+	i := int(warp.BlockPartition{Entities: n, LPs: g}.LP(e))
+	lo = i*d + min(i, m)
+	hi = lo + d
+	if i < m {
+		hi++
+	}
+	return lo, hi
+}
+
+func destination(mitt int, r *rng.Rand) int {
+	n := params.Entities
+	if params.Hot > 0 && r.RandFloat() < params.Hot {
+		if d := int(r.RandIntUniform(0, int32(params.HotSpots-1))); d != mitt {
+			return d
+		}
+	}
+
+	if zipf != nil {
+		d := r.RandEmpirical(zipf)
+		for i := 0; d == mitt && i < 8; i++ {
+			d = r.RandEmpirical(zipf)
+		}
+		if d == mitt { // a very skewed distribution, and mitt is the top entity
+			d = (mitt + 1) % n
+		}
+		return d
+	}
+
+	if params.Remote >= 0 {
+		lo, hi := group(mitt)
+		if r.RandFloat() < params.Remote && hi-lo < n {
+			d := int(r.RandIntUniform(0, int32(n-(hi-lo)-1)))
+			if d >= lo {
+				d += hi - lo
+			}
+			return d
+		}
+		if hi-lo == 1 {
+			return mitt // the only entity of its group
+		}
+		d := lo + int(r.RandIntUniform(0, int32(hi-lo-2)))
+		if d >= mitt {
+			d++
+		}
+		return d
+	}
+
+	dest := int(r.RandIntUniform(0, int32(n-1)))
+	for mitt == dest {
+		dest = int(r.RandIntUniform(0, int32(n-1)))
+	}
+	return dest
+}
+
+func delay(r *rng.Rand) warp.Time {
+	return params.Lookahead + warp.Time(r.RandExponential(params.Mean))
+}
+
+/* the FP operations of an event at time t */
+func workload(t warp.Time) int {
+	fpops := params.FPOps
+	for _, ph := range params.Phases {
+		if ph.Start > t {
+			break
+		}
+		fpops = ph.FPOps
+	}
+	return fpops
+}
+
+func payload(t warp.Time) []byte {
+	if params.Payload == 0 {
+		return nil
+	}
+	b := make([]byte, params.Payload)
+	for i := range b {
+		b[i] = byte(int(t) + i)
+	}
+	return b
+}
+
+func readPayload(b []byte) byte {
+	var sum byte
+	for _, c := range b {
+		sum += c
+	}
+	return sum
+}
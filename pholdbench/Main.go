@@ -0,0 +1,302 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+/*
+ * runs the PHOLD benchmark in-process over a matrix of configurations
+ * (LPs, GOMAXPROCS, entities, density) and reports, for every one of
+ * them, the statistics of the wall clock time over the repetitions and
+ * the speedup and efficiency with respect to the sequential kernel
+ */
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jeffallen/go-warp/models/phold"
+	"github.com/jeffallen/go-warp/warp"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usage = "pholdbench [-lps L,..] [-procs P,..] [-entities E,..] [-density D,..] [-endtime T] [-fpops N] " +
+	"[-reps R] [-warmup W] [-oversubscribe] [-timeout D] [-out FILE] [PHOLD parameters]"
+
+var (
+	lpsFlag  = flag.String("lps", "1,2,4", "numbers of LPs")
+	procFlag = flag.String("procs", "1,2,4", "values of GOMAXPROCS")
+	entFlag  = flag.String("entities", "1000", "numbers of simulated entities")
+	denFlag  = flag.String("density", "0.5", "event densities (initial events per entity)")
+	reps     = flag.Int("reps", 5, "measured repetitions of every configuration")
+	warmup   = flag.Int("warmup", 1, "repetitions run before the measured ones and discarded")
+	oversub  = flag.Bool("oversubscribe", false, "also run the configurations with more LPs than GOMAXPROCS")
+	timeout  = flag.Duration("timeout", 0, "stop a run after this wall clock time, the configuration is reported as cancelled")
+	out      = flag.String("out", "", "write the results to this file (.csv or .json), default CSV on the standard output")
+
+	model = phold.DefaultParams() // set by the flags of phold.RegisterFlags
+)
+
+func init() {
+	phold.RegisterFlags(flag.CommandLine, &model, "entities", "density", "seed") // the matrix, and a seed for each configuration
+}
+
+/* the measures of a configuration */
+type Row struct {
+	Kernel     string  // "seq" for the sequential reference kernel, "warp" otherwise
+	LPs        int     // number of LPs
+	Procs      int     // GOMAXPROCS
+	Entities   int     // simulated entities
+	Density    float64 // initial events per entity
+	Reps       int     // measured repetitions
+	MeanMs     float64 // wall clock time
+	StddevMs   float64
+	CILowMs    float64 // 95% confidence interval of the mean
+	CIHighMs   float64
+	MinMs      float64
+	MaxMs      float64
+	Speedup    float64 // mean time of the sequential kernel / MeanMs
+	Efficiency float64 // Speedup / Procs
+	Committed  float64 // events, mean over the repetitions
+	Rollbacks  float64
+	GvtRounds  float64
+	EventRate  float64 // committed events per second
+	Committing float64 // committed / processed events
+	Cancelled  bool    // at least one repetition was stopped by -timeout
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	warp.Quiet = true // the standard output is for the results
+
+	if err := run(); err != nil {
+		fmt.Println("GO-WARP, ERROR:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	lps, err := parseInts(*lpsFlag)
+	if err != nil {
+		return err
+	}
+	procs, err := parseInts(*procFlag)
+	if err != nil {
+		return err
+	}
+	ents, err := parseInts(*entFlag)
+	if err != nil {
+		return err
+	}
+	dens, err := parseFloats(*denFlag)
+	if err != nil {
+		return err
+	}
+	if *reps < 1 || *warmup < 0 {
+		return fmt.Errorf("invalid number of repetitions %d (warm-up %d)", *reps, *warmup)
+	}
+
+	rows, err := bench(lps, procs, ents, dens)
+	if err != nil {
+		return err
+	}
+	return writeRows(rows, *out)
+}
+
+/*
+ * runs the whole matrix. For every entities and density pair the
+ * sequential kernel is run first, on one core, as the baseline of the
+ * speedup; the initial events do not depend on the number of LPs and the
+ * groups of -remote are the same for all the configurations (by default
+ * one per LP of the largest configuration), so every configuration
+ * executes the same workload
+ */
+func bench(lps, procs, ents []int, dens []float64) ([]Row, error) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(-1))
+
+	var rows []Row
+	for _, ne := range ents {
+		for _, d := range dens {
+			p := model
+			p.Entities, p.Density, p.Seed = ne, d, int64(ne)
+			if p.Groups == 0 {
+				p.Groups = maxInt(lps)
+			}
+			if err := p.Validate(); err != nil {
+				return nil, err
+			}
+
+			base, err := measure(p, 1, 1, true)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, base)
+
+			for _, np := range procs {
+				for _, nl := range lps {
+					if nl > np && !*oversub {
+						continue
+					}
+					r, err := measure(p, nl, np, false)
+					if err != nil {
+						return nil, err
+					}
+					r.Speedup = base.MeanMs / r.MeanMs
+					r.Efficiency = r.Speedup / float64(np)
+					rows = append(rows, r)
+				}
+			}
+		}
+	}
+	return rows, nil
+}
+
+/* runs a configuration -warmup + -reps times */
+func measure(p phold.Params, nl, np int, seq bool) (Row, error) {
+	r := Row{Kernel: "warp", LPs: nl, Procs: np, Entities: p.Entities, Density: p.Density, Reps: *reps}
+	if seq {
+		r.Kernel = "seq"
+	}
+	runtime.GOMAXPROCS(np)
+
+	ms := make([]float64, 0, *reps)
+	var processed float64
+	for i := -*warmup; i < *reps; i++ {
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if *timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, *timeout)
+		}
+		runtime.GC() // the garbage of the previous run is not charged to this one
+
+		start := time.Now()
+		res, err := phold.Run(warp.Config{LPs: nl, Context: ctx, Sequential: seq}, p)
+		elapsed := time.Since(start)
+		cancel()
+		if res == nil {
+			return r, err
+		}
+		if i < 0 {
+			continue
+		}
+
+		s := res.Stats
+		ms = append(ms, float64(elapsed)/float64(time.Millisecond))
+		r.Committed += float64(s.Committed)
+		r.Rollbacks += float64(s.Rollbacks)
+		r.GvtRounds += float64(s.GvtRounds)
+		processed += float64(s.Processed)
+		r.Cancelled = r.Cancelled || s.Cancelled
+	}
+
+	n := float64(*reps)
+	r.MeanMs, r.StddevMs, r.MinMs, r.MaxMs = summary(ms)
+	h := tQuantile(*reps-1) * r.StddevMs / math.Sqrt(n)
+	r.CILowMs, r.CIHighMs = r.MeanMs-h, r.MeanMs+h
+	r.Speedup, r.Efficiency = 1, 1/float64(np)
+	if processed > 0 {
+		r.Committing = r.Committed / processed
+	}
+	r.EventRate = r.Committed / (r.MeanMs * n / 1000)
+	r.Committed /= n
+	r.Rollbacks /= n
+	r.GvtRounds /= n
+
+	fmt.Fprintf(os.Stderr, "%s LPs %d, GOMAXPROCS %d, entities %d, density %v: %.3f ms +- %.3f\n",
+		r.Kernel, nl, np, p.Entities, p.Density, r.MeanMs, h)
+	return r, nil
+}
+
+func writeRows(rows []Row, path string) error {
+	asJSON := strings.HasSuffix(path, ".json")
+	if path == "" {
+		return encodeRows(os.Stdout, rows, asJSON)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = encodeRows(f, rows, asJSON)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+/* writes the rows as JSON or CSV */
+func encodeRows(w io.Writer, rows []Row, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(rows)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"kernel", "lps", "procs", "entities", "density", "reps", "mean_ms", "stddev_ms",
+		"ci_low_ms", "ci_high_ms", "min_ms", "max_ms", "speedup", "efficiency", "committed", "rollbacks",
+		"gvt_rounds", "event_rate", "committing", "cancelled"})
+	for _, r := range rows {
+		cw.Write([]string{r.Kernel, strconv.Itoa(r.LPs), strconv.Itoa(r.Procs), strconv.Itoa(r.Entities),
+			ftoa(r.Density), strconv.Itoa(r.Reps), ftoa(r.MeanMs), ftoa(r.StddevMs), ftoa(r.CILowMs),
+			ftoa(r.CIHighMs), ftoa(r.MinMs), ftoa(r.MaxMs), ftoa(r.Speedup), ftoa(r.Efficiency),
+			ftoa(r.Committed), ftoa(r.Rollbacks), ftoa(r.GvtRounds), ftoa(r.EventRate), ftoa(r.Committing),
+			strconv.FormatBool(r.Cancelled)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func ftoa(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
+func maxInt(v []int) int {
+	m := v[0]
+	for _, x := range v {
+		m = max(m, x)
+	}
+	return m
+}
+
+func parseInts(s string) ([]int, error) {
+	var v []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid value %q in %q", f, s)
+		}
+		v = append(v, n)
+	}
+	return v, nil
+}
+
+func parseFloats(s string) ([]float64, error) {
+	var v []float64
+	for _, f := range strings.Split(s, ",") {
+		x, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil || x <= 0 {
+			return nil, fmt.Errorf("invalid value %q in %q", f, s)
+		}
+		v = append(v, x)
+	}
+	return v, nil
+}
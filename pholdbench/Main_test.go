@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+/* every configuration of the matrix executes the workload of the sequential run */
+func TestBench(t *testing.T) {
+	model.EndTime, model.FPOps, *reps, *warmup = 100, 0, 2, 0
+	rows, err := bench([]int{1, 2}, []int{1, 2}, []int{16, 32}, []float64{0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	/* for every number of entities: the sequential run, 1 LP on 1 core, 1 and 2 LPs on 2 cores */
+	want := []struct {
+		kernel           string
+		lps, procs, ents int
+	}{
+		{"seq", 1, 1, 16}, {"warp", 1, 1, 16}, {"warp", 1, 2, 16}, {"warp", 2, 2, 16},
+		{"seq", 1, 1, 32}, {"warp", 1, 1, 32}, {"warp", 1, 2, 32}, {"warp", 2, 2, 32},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("%d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	var base Row
+	for i, r := range rows {
+		w := want[i]
+		if r.Kernel != w.kernel || r.LPs != w.lps || r.Procs != w.procs || r.Entities != w.ents || r.Reps != 2 {
+			t.Fatalf("row %d is %+v, want %+v", i, r, w)
+		}
+		if r.Kernel == "seq" {
+			base = r
+		}
+		if r.Committed == 0 || r.Committed != base.Committed {
+			t.Fatalf("row %d: %v committed events, the sequential run committed %v", i, r.Committed, base.Committed)
+		}
+		if r.MinMs > r.MeanMs || r.MeanMs > r.MaxMs || r.CILowMs > r.MeanMs || r.CIHighMs < r.MeanMs {
+			t.Fatalf("row %d: inconsistent times %+v", i, r)
+		}
+		if r.Efficiency != r.Speedup/float64(r.Procs) {
+			t.Fatalf("row %d: efficiency %v with speedup %v on %d cores", i, r.Efficiency, r.Speedup, r.Procs)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := encodeRows(&b, rows, false); err != nil {
+		t.Fatal(err)
+	}
+	recs, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != len(rows)+1 || len(recs[0]) != len(recs[1]) {
+		t.Fatalf("%d CSV records of %d fields for %d rows", len(recs), len(recs[0]), len(rows))
+	}
+}
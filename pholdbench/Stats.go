@@ -0,0 +1,57 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package main
+
+import (
+	"math"
+)
+
+/* 0.975 quantiles of the Student's t distribution, by degrees of freedom */
+var tTable = [...]float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+/* the half width of a 95% confidence interval is tQuantile(n-1) * stddev / sqrt(n) */
+func tQuantile(df int) float64 {
+	if df < 1 {
+		return 0 // a single repetition, no interval
+	}
+	if df <= len(tTable) {
+		return tTable[df-1]
+	}
+	return 1.96
+}
+
+/* mean, sample standard deviation, minimum and maximum of x */
+func summary(x []float64) (mean, stddev, min, max float64) {
+	if len(x) == 0 {
+		return
+	}
+	min, max = x[0], x[0]
+	for _, v := range x {
+		mean += v
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	mean /= float64(len(x))
+	if len(x) > 1 {
+		for _, v := range x {
+			stddev += (v - mean) * (v - mean)
+		}
+		stddev = math.Sqrt(stddev / float64(len(x)-1))
+	}
+	return
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	mean, stddev, min, max := summary([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if mean != 5 || math.Abs(stddev-math.Sqrt(32.0/7)) > 1e-12 || min != 2 || max != 9 {
+		t.Fatalf("summary: mean %v, stddev %v, min %v, max %v", mean, stddev, min, max)
+	}
+	if mean, stddev, min, max = summary([]float64{3}); mean != 3 || stddev != 0 || min != 3 || max != 3 {
+		t.Fatalf("summary of one repetition: mean %v, stddev %v, min %v, max %v", mean, stddev, min, max)
+	}
+	if mean, stddev, min, max = summary(nil); mean != 0 || stddev != 0 || min != 0 || max != 0 {
+		t.Fatalf("summary of no repetition: mean %v, stddev %v, min %v, max %v", mean, stddev, min, max)
+	}
+}
+
+func TestTQuantile(t *testing.T) {
+	for df, want := range map[int]float64{0: 0, 1: 12.706, 4: 2.776, 30: 2.042, 31: 1.96, 1000: 1.96} {
+		if q := tQuantile(df); q != want {
+			t.Fatalf("tQuantile(%d) = %v, want %v", df, q, want)
+		}
+	}
+	for df := 2; df <= len(tTable); df++ {
+		if tQuantile(df) >= tQuantile(df-1) {
+			t.Fatalf("tQuantile(%d) = %v is not smaller than tQuantile(%d) = %v", df, tQuantile(df), df-1, tQuantile(df-1))
+		}
+	}
+}
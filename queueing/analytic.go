@@ -0,0 +1,126 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package queueing
+
+/*
+ * ANALYTIC RESULTS
+ *
+ * the steady state of the M/M/1 and M/M/c queues and of the open Jackson
+ * networks, against which the simulated statistics are validated
+ */
+
+import (
+	"fmt"
+	"math"
+)
+
+type Metrics struct {
+	Lambda float64 // arrival rate
+	Rho    float64 // utilization of a server
+	L      float64 // mean jobs in the station
+	Lq     float64 // mean waiting jobs
+	W      float64 // mean time in the station
+	Wq     float64 // mean waiting time
+}
+
+/* a single server queue with arrival rate lambda and service rate mu */
+func MM1(lambda, mu float64) Metrics {
+	return MMc(lambda, mu, 1)
+}
+
+/* c servers with service rate mu, the probability of waiting is given by the Erlang C formula */
+func MMc(lambda, mu float64, c int) Metrics {
+	a := lambda / mu
+	rho := a / float64(c)
+	if rho >= 1 {
+		inf := math.Inf(1)
+		return Metrics{lambda, rho, inf, inf, inf, inf}
+	}
+
+	/* Erlang B by recursion, then C */
+	b := 1.0
+	for k := 1; k <= c; k++ {
+		b = a * b / (float64(k) + a*b)
+	}
+	pw := b / (1 - rho*(1-b))
+
+	lq := pw * rho / (1 - rho)
+	wq := lq / lambda
+	return Metrics{lambda, rho, lq + a, lq, wq + 1/mu, wq}
+}
+
+/*
+ * the steady state of the queues of an open Jackson network: the queues
+ * have unlimited capacity and the routers are probabilistic. The arrival
+ * rates are the solution of the traffic equations
+ */
+func Jackson(t Topology) (map[string]Metrics, error) {
+	n, err := New(t)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range n.Queues {
+		if q.Capacity > 0 {
+			return nil, fmt.Errorf("queueing: %s: a Jackson network has no queue with a limited capacity", q.Name)
+		}
+	}
+	for _, r := range n.Routers {
+		if r.Policy != "" && r.Policy != "probabilistic" {
+			return nil, fmt.Errorf("queueing: %s: a Jackson network has probabilistic routers only", r.Name)
+		}
+	}
+
+	/* lambda = external + lambda * routing, by iteration */
+	lambda := make([]float64, len(n.comps))
+	for i := 0; ; i++ {
+		if i == 100000 {
+			return nil, fmt.Errorf("queueing: the traffic equations do not converge, is the network closed?")
+		}
+		next := make([]float64, len(n.comps))
+		for k, c := range n.comps {
+			switch c := c.(type) {
+			case *source:
+				next[c.to] += c.spec.Rate
+			case *queue:
+				next[c.to] += lambda[k]
+			case *router:
+				prev := 0.0
+				for d, p := range c.cumul {
+					next[c.to[d]] += lambda[k] * (p - prev)
+					prev = p
+				}
+			}
+		}
+		diff := 0.0
+		for k := range next {
+			diff = math.Max(diff, math.Abs(next[k]-lambda[k]))
+		}
+		lambda = next
+		if diff < 1e-12 {
+			break
+		}
+	}
+
+	ret := make(map[string]Metrics)
+	for k, c := range n.comps {
+		if q, ok := c.(*queue); ok {
+			m := MMc(lambda[k], q.spec.Rate, q.spec.Servers)
+			if m.Rho >= 1 {
+				return nil, fmt.Errorf("queueing: %s: unstable queue, utilization %v", q.name, m.Rho)
+			}
+			ret[q.name] = m
+		}
+	}
+	return ret, nil
+}
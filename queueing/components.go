@@ -0,0 +1,367 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package queueing
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/jeffallen/go-warp/warp"
+)
+
+/* a job, carried by the JOB and DEPARTURE events */
+type Job struct {
+	Source   int       // the entity of the source that has generated the job
+	Seq      int64     // the jobs of a source are numbered from 0
+	Priority int       // see SourceSpec
+	Created  warp.Time // by the source
+	Arrived  warp.Time // at the current queue
+	Started  warp.Time // the service at the current queue
+	Notify   int       // the "shortest" router to notify when the job leaves the queue, -1 if none
+}
+
+const jobSize = 7 * 8
+
+func (j *Job) encode() []byte {
+	b := make([]byte, jobSize)
+	for i, v := range []int64{int64(j.Source), j.Seq, int64(j.Priority), int64(j.Created),
+		int64(j.Arrived), int64(j.Started), int64(j.Notify)} {
+		binary.LittleEndian.PutUint64(b[8*i:], uint64(v))
+	}
+	return b
+}
+
+func decodeJob(b []byte) (Job, error) {
+	if len(b) != jobSize {
+		return Job{}, fmt.Errorf("invalid job of %d bytes", len(b))
+	}
+	v := make([]int64, 7)
+	for i := range v {
+		v[i] = int64(binary.LittleEndian.Uint64(b[8*i:]))
+	}
+	return Job{int(v[0]), v[1], int(v[2]), warp.Time(v[3]), warp.Time(v[4]), warp.Time(v[5]), int(v[6])}, nil
+}
+
+type base struct {
+	net  *Network
+	id   int // the entity
+	name string
+}
+
+/* sends the job to entity to, one tick later */
+func (b *base) send(j *Job, to int, t warp.Time, l *warp.LocalData) {
+	ev := warp.CreateEvent(0, t+1, warp.Info{Flag: JOB, Data: j.encode()})
+	notice(ev, to, l)
+}
+
+type source struct {
+	base
+	spec SourceSpec
+	to   int
+	seq  int64 // the state: the number of the next job
+	acc  accumulator
+}
+
+func (s *source) Handle(ev *warp.Event, l *warp.LocalData) {
+	if ev.Type.Flag != NEXT {
+		unexpected(ev, l)
+		return
+	}
+	next := warp.CreateEvent(0, s.net.after(ev.Time, l.Rand().RandExponential(1/s.spec.Rate)), warp.Info{Flag: NEXT})
+	notice(next, s.id, l)
+
+	j := Job{Source: s.id, Seq: s.seq, Priority: s.spec.Priority, Created: ev.Time, Notify: -1}
+	s.seq++
+	s.send(&j, s.to, ev.Time, l)
+}
+
+func (s *source) Save() interface{} {
+	return s.seq
+}
+
+func (s *source) Restore(st interface{}) {
+	s.seq = st.(int64)
+}
+
+func (s *source) Commit(ev *warp.Event) {
+	s.acc.advance(ev.Time)
+	s.acc.arrivals++
+}
+
+func (s *source) stats(end warp.Time) Stats {
+	return s.acc.stats(s.net, end, s.name, "source")
+}
+
+func (s *source) reset() {
+	s.seq, s.acc = 0, accumulator{}
+}
+
+type queue struct {
+	base
+	spec  QueueSpec
+	to    int
+	state queueState
+	acc   accumulator
+}
+
+type queueState struct {
+	Busy    int   // servers
+	Waiting []Job // in the order of service
+}
+
+func (q *queue) Handle(ev *warp.Event, l *warp.LocalData) {
+	if ev.Type.Flag != JOB && ev.Type.Flag != DEPARTURE {
+		unexpected(ev, l)
+		return
+	}
+	j, err := decodeJob(ev.Type.Data)
+	if err != nil {
+		l.Fail(err)
+		return
+	}
+	switch ev.Type.Flag {
+	case JOB:
+		j.Arrived = ev.Time
+		if q.spec.Capacity > 0 && q.state.Busy+len(q.state.Waiting) >= q.spec.Capacity {
+			q.leave(&j, ev.Time, l) // lost
+			return
+		}
+		if q.state.Busy < q.spec.Servers {
+			q.start(&j, ev.Time, l)
+			return
+		}
+		q.enqueue(j)
+	case DEPARTURE:
+		q.state.Busy--
+		q.leave(&j, ev.Time, l)
+		q.send(&j, q.to, ev.Time, l)
+		if len(q.state.Waiting) > 0 {
+			next := q.state.Waiting[0]
+			q.state.Waiting = q.state.Waiting[1:]
+			q.start(&next, ev.Time, l)
+		}
+	}
+}
+
+/* the priority queues keep the jobs of the same priority in FIFO order */
+func (q *queue) enqueue(j Job) {
+	w := q.state.Waiting
+	i := len(w)
+	if q.spec.Discipline == "priority" {
+		for i > 0 && w[i-1].Priority > j.Priority {
+			i--
+		}
+	}
+	w = append(w, Job{})
+	copy(w[i+1:], w[i:])
+	w[i] = j
+	q.state.Waiting = w
+}
+
+func (q *queue) start(j *Job, t warp.Time, l *warp.LocalData) {
+	q.state.Busy++
+	j.Started = t
+	d := l.Rand().RandExponential(1 / q.spec.Rate)
+	ev := warp.CreateEvent(0, q.net.after(t, d), warp.Info{Flag: DEPARTURE, Data: j.encode()})
+	notice(ev, q.id, l)
+}
+
+/* the job leaves the queue, served or lost: the router that has chosen the queue is notified */
+func (q *queue) leave(j *Job, t warp.Time, l *warp.LocalData) {
+	if j.Notify < 0 {
+		return
+	}
+	notice(warp.CreateEvent(0, t+1, warp.Info{Flag: DONE}), j.Notify, l)
+	j.Notify = -1
+}
+
+func (q *queue) Save() interface{} {
+	s := q.state
+	s.Waiting = append([]Job(nil), s.Waiting...)
+	return s
+}
+
+func (q *queue) Restore(st interface{}) {
+	q.state = st.(queueState)
+	q.state.Waiting = append([]Job(nil), q.state.Waiting...)
+}
+
+/* the statistics replay the arrivals and the departures, see accumulator */
+func (q *queue) Commit(ev *warp.Event) {
+	a := &q.acc
+	a.advance(ev.Time)
+	switch ev.Type.Flag {
+	case JOB:
+		if q.spec.Capacity > 0 && a.jobs >= q.spec.Capacity {
+			a.lost++
+			return
+		}
+		a.arrivals++
+		a.jobs++
+	case DEPARTURE:
+		j, _ := decodeJob(ev.Type.Data) // checked by Handle
+		a.departures++
+		a.jobs--
+		a.wait += q.net.Units(j.Started - j.Arrived)
+		a.sojourn += q.net.Units(ev.Time - j.Arrived)
+	}
+}
+
+func (q *queue) stats(end warp.Time) Stats {
+	return q.acc.stats(q.net, end, q.name, "queue")
+}
+
+func (q *queue) reset() {
+	q.state, q.acc = queueState{}, accumulator{servers: q.spec.Servers}
+}
+
+type router struct {
+	base
+	spec  RouterSpec
+	to    []int     // the entities of the destinations
+	cumul []float64 // the cumulative probabilities of the destinations
+	state routerState
+	acc   accumulator
+}
+
+type routerState struct {
+	Next  int   // the next destination of the round robin policy
+	Count []int // of the shortest queue policy: the jobs sent to a queue that have not left it yet
+}
+
+func (r *router) Handle(ev *warp.Event, l *warp.LocalData) {
+	switch ev.Type.Flag {
+	case JOB:
+		j, err := decodeJob(ev.Type.Data)
+		if err != nil {
+			l.Fail(err)
+			return
+		}
+		i := r.choose(l)
+		if r.spec.Policy == "shortest" {
+			r.state.Count[i]++
+			j.Notify = r.id
+		}
+		r.send(&j, r.to[i], ev.Time, l)
+	case DONE:
+		for i, to := range r.to {
+			if to == ev.Type.From {
+				r.state.Count[i]--
+				break
+			}
+		}
+	default:
+		unexpected(ev, l)
+	}
+}
+
+/* the index of the destination of a job */
+func (r *router) choose(l *warp.LocalData) int {
+	switch r.spec.Policy {
+	case "roundrobin":
+		i := r.state.Next
+		r.state.Next = (i + 1) % len(r.to)
+		return i
+	case "shortest":
+		min := 0
+		for i, c := range r.state.Count {
+			if c < r.state.Count[min] {
+				min = i
+			}
+		}
+		return min
+	}
+	u := l.Rand().RandFloat()
+	for i, c := range r.cumul {
+		if u < c {
+			return i
+		}
+	}
+	return len(r.cumul) - 1
+}
+
+func (r *router) Save() interface{} {
+	s := r.state
+	s.Count = append([]int(nil), s.Count...)
+	return s
+}
+
+func (r *router) Restore(st interface{}) {
+	r.state = st.(routerState)
+	r.state.Count = append([]int(nil), r.state.Count...)
+}
+
+func (r *router) Commit(ev *warp.Event) {
+	r.acc.advance(ev.Time)
+	if ev.Type.Flag == JOB {
+		r.acc.arrivals++
+	}
+}
+
+func (r *router) stats(end warp.Time) Stats {
+	return r.acc.stats(r.net, end, r.name, "router")
+}
+
+func (r *router) reset() {
+	r.state, r.acc = routerState{Count: make([]int, len(r.to))}, accumulator{}
+}
+
+/* a sink has no state, its statistics are those of the absorbed jobs */
+type sink struct {
+	base
+	acc accumulator
+}
+
+func (s *sink) Handle(ev *warp.Event, l *warp.LocalData) {
+	if ev.Type.Flag != JOB {
+		unexpected(ev, l)
+		return
+	}
+	if _, err := decodeJob(ev.Type.Data); err != nil {
+		l.Fail(err)
+	}
+}
+
+func (s *sink) Save() interface{} {
+	return nil
+}
+
+func (s *sink) Restore(st interface{}) {}
+
+func (s *sink) Commit(ev *warp.Event) {
+	j, _ := decodeJob(ev.Type.Data) // checked by Handle
+	s.acc.advance(ev.Time)
+	s.acc.arrivals++
+	s.acc.departures++
+	s.acc.sojourn += s.net.Units(ev.Time - j.Created)
+}
+
+func (s *sink) stats(end warp.Time) Stats {
+	return s.acc.stats(s.net, end, s.name, "sink")
+}
+
+func (s *sink) reset() {
+	s.acc = accumulator{}
+}
+
+/* sends the event to entity to, an event refused by the kernel stops the simulation */
+func notice(ev *warp.Event, to int, l *warp.LocalData) {
+	if err := warp.NoticeEntity(ev, to, l); err != nil {
+		l.Fail(fmt.Errorf("event not sent to the component %d: %w", to, err))
+	}
+}
+
+/* an event of an unknown kind stops the simulation */
+func unexpected(ev *warp.Event, l *warp.LocalData) {
+	l.Fail(fmt.Errorf("unexpected event %d for the component %d", ev.Type.Flag, ev.Type.To))
+}
@@ -0,0 +1,322 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package queueing
+
+/*
+ * QUEUEING NETWORKS
+ *
+ * a network is made of sources, queues (M/M/c stations), routers and
+ * sinks, described by a Topology and wired by name: every component is an
+ * entity of the kernel, the jobs are the payload of the events that move
+ * between them. The times of the model are float64 time units, the kernel
+ * times are ticks: a time unit is Scale ticks, and every move of a job
+ * takes one tick (the lookahead of the model).
+ *
+ * The state of the components (the waiting jobs, the busy servers, the
+ * routing state) is saved and restored by the kernel; the statistics are
+ * computed from the committed events only (see stats.go), so they are
+ * the same with any kernel and are never rolled back.
+ */
+
+import (
+	"fmt"
+	"github.com/jeffallen/go-warp/warp"
+)
+
+const DEFAULTSCALE = 1000
+
+type Topology struct {
+	Scale   float64      `json:"scale"` // ticks per time unit, 0 means DEFAULTSCALE
+	Sources []SourceSpec `json:"sources"`
+	Queues  []QueueSpec  `json:"queues"`
+	Routers []RouterSpec `json:"routers"`
+	Sinks   []SinkSpec   `json:"sinks"`
+}
+
+/* generates jobs with exponential interarrival times */
+type SourceSpec struct {
+	Name     string  `json:"name"`
+	Rate     float64 `json:"rate"`     // jobs per time unit
+	Priority int     `json:"priority"` // of its jobs, the lower the sooner in a "priority" queue
+	To       string  `json:"to"`
+}
+
+/* a station with Servers servers and exponential service times */
+type QueueSpec struct {
+	Name       string  `json:"name"`
+	Servers    int     `json:"servers"`    // 0 means 1
+	Rate       float64 `json:"rate"`       // jobs served per time unit by a server
+	Discipline string  `json:"discipline"` // "fifo" (the default) or "priority" (not preemptive)
+	Capacity   int     `json:"capacity"`   // jobs in the station, the others are lost; 0 means no limit
+	To         string  `json:"to"`
+}
+
+/* forwards every job to one of To */
+type RouterSpec struct {
+	Name    string    `json:"name"`
+	Policy  string    `json:"policy"`  // "probabilistic" (the default), "roundrobin" or "shortest"
+	To      []string  `json:"to"`      // with "shortest", queues only
+	Weights []float64 `json:"weights"` // of the probabilistic policy, nil means uniform
+}
+
+/* absorbs the jobs */
+type SinkSpec struct {
+	Name string `json:"name"`
+}
+
+/* the kinds of the events (Info.Flag) */
+const (
+	JOB       = iota + 1 // a job arrives at a component
+	NEXT                 // a source generates its next job
+	DEPARTURE            // a job has been served
+	DONE                 // a job has left a queue chosen by a "shortest" router
+)
+
+type Network struct {
+	Topology
+	comps []component // the entities: the sources, the queues, the routers and the sinks
+	index map[string]int
+	end   warp.Time // of the last run
+}
+
+/* a component of the network, implements warp.Entity and warp.Committer */
+type component interface {
+	warp.Entity
+	warp.Committer
+	stats(end warp.Time) Stats
+	reset() // empties the component before a run
+}
+
+/* checks and wires the topology t */
+func New(t Topology) (*Network, error) {
+	if t.Scale == 0 {
+		t.Scale = DEFAULTSCALE
+	}
+	if t.Scale < 1 {
+		return nil, fmt.Errorf("queueing: invalid scale %v", t.Scale)
+	}
+	n := &Network{Topology: t, index: make(map[string]int)}
+
+	var names []string
+	for _, s := range t.Sources {
+		names = append(names, s.Name)
+	}
+	for _, q := range t.Queues {
+		names = append(names, q.Name)
+	}
+	for _, r := range t.Routers {
+		names = append(names, r.Name)
+	}
+	for _, s := range t.Sinks {
+		names = append(names, s.Name)
+	}
+	for i, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("queueing: component %d has no name", i)
+		}
+		if _, ok := n.index[name]; ok {
+			return nil, fmt.Errorf("queueing: %s: duplicated name", name)
+		}
+		n.index[name] = i
+	}
+
+	for _, s := range t.Sources {
+		to, err := n.target(s.Name, s.To)
+		if err != nil {
+			return nil, err
+		}
+		if s.Rate <= 0 {
+			return nil, fmt.Errorf("queueing: %s: invalid rate %v", s.Name, s.Rate)
+		}
+		n.comps = append(n.comps, &source{base: base{n, len(n.comps), s.Name}, spec: s, to: to})
+	}
+	for _, q := range t.Queues {
+		to, err := n.target(q.Name, q.To)
+		if err != nil {
+			return nil, err
+		}
+		if q.Servers == 0 {
+			q.Servers = 1
+		}
+		if q.Discipline == "" {
+			q.Discipline = "fifo"
+		}
+		switch {
+		case q.Rate <= 0:
+			return nil, fmt.Errorf("queueing: %s: invalid rate %v", q.Name, q.Rate)
+		case q.Servers < 0 || q.Capacity < 0:
+			return nil, fmt.Errorf("queueing: %s: invalid servers or capacity", q.Name)
+		case q.Discipline != "fifo" && q.Discipline != "priority":
+			return nil, fmt.Errorf("queueing: %s: unknown discipline %q", q.Name, q.Discipline)
+		}
+		n.comps = append(n.comps, &queue{base: base{n, len(n.comps), q.Name}, spec: q, to: to})
+	}
+	for _, r := range t.Routers {
+		rt, err := n.router(r)
+		if err != nil {
+			return nil, err
+		}
+		n.comps = append(n.comps, rt)
+	}
+	for _, s := range t.Sinks {
+		n.comps = append(n.comps, &sink{base: base{n, len(n.comps), s.Name}})
+	}
+	return n, nil
+}
+
+func (n *Network) router(r RouterSpec) (*router, error) {
+	if r.Policy == "" {
+		r.Policy = "probabilistic"
+	}
+	if len(r.To) == 0 {
+		return nil, fmt.Errorf("queueing: %s: no destination", r.Name)
+	}
+	rt := &router{base: base{n, len(n.comps), r.Name}, spec: r}
+	for _, name := range r.To {
+		to, err := n.target(r.Name, name)
+		if err != nil {
+			return nil, err
+		}
+		if r.Policy == "shortest" && n.index[name] >= len(n.Sources)+len(n.Queues) {
+			return nil, fmt.Errorf("queueing: %s: the shortest queue policy needs queues, not %s", r.Name, name)
+		}
+		rt.to = append(rt.to, to)
+	}
+
+	switch r.Policy {
+	case "probabilistic":
+		w := r.Weights
+		if w == nil {
+			w = make([]float64, len(r.To))
+			for i := range w {
+				w[i] = 1
+			}
+		}
+		if len(w) != len(r.To) {
+			return nil, fmt.Errorf("queueing: %s: %d weights for %d destinations", r.Name, len(w), len(r.To))
+		}
+		sum := 0.0
+		for _, x := range w {
+			if x < 0 {
+				return nil, fmt.Errorf("queueing: %s: negative weight %v", r.Name, x)
+			}
+			sum += x
+		}
+		if sum == 0 {
+			return nil, fmt.Errorf("queueing: %s: all the weights are 0", r.Name)
+		}
+		acc := 0.0
+		for _, x := range w {
+			acc += x / sum
+			rt.cumul = append(rt.cumul, acc)
+		}
+	case "roundrobin", "shortest":
+		if r.Weights != nil {
+			return nil, fmt.Errorf("queueing: %s: weights with the %s policy", r.Name, r.Policy)
+		}
+	default:
+		return nil, fmt.Errorf("queueing: %s: unknown policy %q", r.Name, r.Policy)
+	}
+	rt.state.Count = make([]int, len(rt.to))
+	return rt, nil
+}
+
+/* the entity of the destination name of component from, that cannot be a source */
+func (n *Network) target(from, name string) (int, error) {
+	i, ok := n.index[name]
+	if !ok {
+		return 0, fmt.Errorf("queueing: %s: unknown destination %q", from, name)
+	}
+	if i < len(n.Sources) {
+		return 0, fmt.Errorf("queueing: %s: the destination %s is a source", from, name)
+	}
+	return i, nil
+}
+
+/* the ticks of t time units */
+func (n *Network) Ticks(t float64) warp.Time {
+	return warp.Time(t * n.Scale)
+}
+
+/* the time units of t ticks */
+func (n *Network) Units(t warp.Time) float64 {
+	return float64(t) / n.Scale
+}
+
+/* the time of an event d time units after t, at least the next tick */
+func (n *Network) after(t warp.Time, d float64) warp.Time {
+	return t + 1 + warp.Time(d*n.Scale)
+}
+
+/* the entities of the network, in the order of the topology: sources, queues, routers, sinks */
+func (n *Network) Entities() []warp.Entity {
+	ents := make([]warp.Entity, len(n.comps))
+	for i := range ents {
+		ents[i] = n.comps[i]
+	}
+	return ents
+}
+
+/* the entity of the component name, -1 if there is none */
+func (n *Network) Entity(name string) int {
+	if i, ok := n.index[name]; ok {
+		return i
+	}
+	return -1
+}
+
+/* each LP schedules the first job of the sources it owns */
+func (n *Network) InitLP(l *warp.LocalData) error {
+	for i := range n.Sources {
+		if warp.EntityLP(i) != l.IndexLP {
+			continue
+		}
+		s := n.comps[i].(*source)
+		t := n.after(0, warp.EntityStream(i).RandExponential(1/s.spec.Rate))
+		l.NewEvent(warp.CreateEvent(0, t, warp.Info{From: i, To: i, Flag: NEXT}))
+	}
+	return nil
+}
+
+/*
+ * runs the network up to cfg.EndTime (in ticks, see Ticks), the other
+ * options are taken from cfg. A network can be run more than once, every
+ * run starts from an empty network
+ */
+func (n *Network) Run(cfg warp.Config) (*warp.Result, error) {
+	for _, c := range n.comps {
+		c.reset()
+	}
+	n.end = cfg.EndTime
+	cfg.Entities = n.Entities()
+	return warp.Run(cfg, n.InitLP)
+}
+
+/* the statistics of the components at the end of the last run */
+func (n *Network) Stats() []Stats {
+	ret := make([]Stats, len(n.comps))
+	for i, c := range n.comps {
+		ret[i] = c.stats(n.end)
+	}
+	return ret
+}
+
+/* the statistics of the component name at the end of the last run */
+func (n *Network) Station(name string) Stats {
+	i := n.Entity(name)
+	if i < 0 {
+		return Stats{}
+	}
+	return n.comps[i].stats(n.end)
+}
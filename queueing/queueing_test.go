@@ -0,0 +1,188 @@
+package queueing
+
+import (
+	"github.com/jeffallen/go-warp/warp"
+	"github.com/jeffallen/go-warp/warp/warptest"
+	"math"
+	"testing"
+)
+
+/* a source, a queue with feedback through a router, a two servers queue and a sink */
+func jacksonTopology() Topology {
+	return Topology{
+		Sources: []SourceSpec{{Name: "in", Rate: 1, To: "q1"}},
+		Queues: []QueueSpec{{Name: "q1", Rate: 2, To: "r"},
+			{Name: "q2", Servers: 2, Rate: 1, To: "out"}},
+		Routers: []RouterSpec{{Name: "r", To: []string{"q1", "q2"}, Weights: []float64{0.3, 0.7}}},
+		Sinks:   []SinkSpec{{Name: "out"}},
+	}
+}
+
+func run(t *testing.T, top Topology, cfg warp.Config, end float64) *Network {
+	n, err := New(top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.EndTime = n.Ticks(end)
+	if _, err := n.Run(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func near(got, want, tol float64) bool {
+	return math.Abs(got-want) <= tol*math.Abs(want)
+}
+
+func TestAnalytic(t *testing.T) {
+	m := MM1(0.5, 1)
+	if m.L != 1 || m.W != 2 || m.Lq != 0.5 || m.Wq != 1 {
+		t.Fatalf("M/M/1: %+v", m)
+	}
+	/* M/M/2 with a = 1: the probability of waiting is 1/3 */
+	if m := MMc(1, 1, 2); !near(m.Lq, 1.0/3, 1e-12) || !near(m.L, 4.0/3, 1e-12) {
+		t.Fatalf("M/M/2: %+v", m)
+	}
+	j, err := Jackson(jacksonTopology())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(j["q1"].Lambda, 1/0.7, 1e-9) || !near(j["q2"].Lambda, 1, 1e-9) {
+		t.Fatalf("traffic equations: %+v", j)
+	}
+}
+
+func TestTopologyErrors(t *testing.T) {
+	bad := []Topology{
+		{Sources: []SourceSpec{{Name: "s", Rate: 1, To: "nowhere"}}},
+		{Sources: []SourceSpec{{Name: "s", Rate: 1, To: "s"}}},
+		{Sources: []SourceSpec{{Name: "s", Rate: 1, To: "k"}}, Sinks: []SinkSpec{{Name: "s"}}},
+		{Queues: []QueueSpec{{Name: "q", Rate: 1, To: "k", Discipline: "lifo"}}, Sinks: []SinkSpec{{Name: "k"}}},
+		{Routers: []RouterSpec{{Name: "r", To: []string{"k"}, Policy: "shortest"}}, Sinks: []SinkSpec{{Name: "k"}}},
+		{Routers: []RouterSpec{{Name: "r", To: []string{"k"}, Weights: []float64{1, 2}}}, Sinks: []SinkSpec{{Name: "k"}}},
+	}
+	for i, top := range bad {
+		if _, err := New(top); err == nil {
+			t.Fatalf("topology %d: no error", i)
+		} else {
+			t.Log(err)
+		}
+	}
+}
+
+/* the simulated M/M/1 and Jackson network have the analytic steady state */
+func TestSteadyState(t *testing.T) {
+	top := Topology{
+		Sources: []SourceSpec{{Name: "in", Rate: 0.7, To: "q"}},
+		Queues:  []QueueSpec{{Name: "q", Rate: 1, To: "out"}},
+		Sinks:   []SinkSpec{{Name: "out"}},
+	}
+	n := run(t, top, warp.Config{LPs: 1, Sequential: true, Seed: 1}, 200000)
+	s, m := n.Station("q"), MM1(0.7, 1)
+	if !near(s.MeanSojourn, m.W, 0.05) || !near(s.MeanJobs, m.L, 0.05) || !near(s.Utilization, m.Rho, 0.02) {
+		t.Fatalf("M/M/1: %+v, expected %+v", s, m)
+	}
+
+	n = run(t, jacksonTopology(), warp.Config{LPs: 1, Sequential: true, Seed: 1}, 200000)
+	j, _ := Jackson(jacksonTopology())
+	for _, q := range []string{"q1", "q2"} {
+		s, m := n.Station(q), j[q]
+		if !near(s.MeanSojourn, m.W, 0.05) || !near(s.MeanQueue, m.Lq, 0.1) || !near(s.Utilization, m.Rho, 0.02) {
+			t.Fatalf("%s: %+v, expected %+v", q, s, m)
+		}
+	}
+	out := n.Station("out")
+	if !near(out.Throughput, 1, 0.02) {
+		t.Fatalf("throughput %v, expected 1", out.Throughput)
+	}
+}
+
+/* the priority queues serve the lowest priority first, in FIFO order */
+func TestPriority(t *testing.T) {
+	q := &queue{spec: QueueSpec{Discipline: "priority"}}
+	for i, p := range []int{2, 1, 2, 0, 1} {
+		q.enqueue(Job{Seq: int64(i), Priority: p})
+	}
+	for i, want := range []int64{3, 1, 4, 0, 2} {
+		if q.state.Waiting[i].Seq != want {
+			t.Fatalf("priority queue: %+v", q.state.Waiting)
+		}
+	}
+}
+
+/* the shortest queue policy beats the random one, the jobs in excess are lost */
+func TestPolicies(t *testing.T) {
+	top := Topology{
+		Sources: []SourceSpec{{Name: "hi", Rate: 0.4, Priority: 0, To: "q"}, {Name: "lo", Rate: 0.4, Priority: 1, To: "q"}},
+		Queues:  []QueueSpec{{Name: "q", Rate: 1, Discipline: "priority", To: "r"}},
+		Routers: []RouterSpec{{Name: "r", To: []string{"hi-out", "lo-out"}, Policy: "roundrobin"}},
+		Sinks:   []SinkSpec{{Name: "hi-out"}, {Name: "lo-out"}},
+	}
+	n := run(t, top, warp.Config{LPs: 1, Sequential: true, Seed: 2}, 50000)
+	if s := n.Station("q"); !near(s.Utilization, 0.8, 0.05) {
+		t.Fatalf("priority queue: %+v", s)
+	}
+	if a, b := n.Station("hi-out").Arrivals, n.Station("lo-out").Arrivals; a-b > 1 || b-a > 1 {
+		t.Fatalf("round robin: %d and %d jobs", a, b)
+	}
+
+	var w [2]float64
+	for i, policy := range []string{"probabilistic", "shortest"} {
+		top := Topology{
+			Sources: []SourceSpec{{Name: "in", Rate: 1.6, To: "r"}},
+			Routers: []RouterSpec{{Name: "r", To: []string{"a", "b"}, Policy: policy}},
+			Queues:  []QueueSpec{{Name: "a", Rate: 1, To: "out"}, {Name: "b", Rate: 1, To: "out"}},
+			Sinks:   []SinkSpec{{Name: "out"}},
+		}
+		n := run(t, top, warp.Config{LPs: 1, Sequential: true, Seed: 3}, 50000)
+		w[i] = n.Station("out").MeanSojourn
+	}
+	if !near(w[0], MM1(0.8, 1).W, 0.1) || w[1] >= 0.75*w[0] {
+		t.Fatalf("response time %v with the random policy and %v with the shortest queue", w[0], w[1])
+	}
+
+	/* the jobs in excess are lost */
+	top = Topology{
+		Sources: []SourceSpec{{Name: "in", Rate: 1, To: "q"}},
+		Queues:  []QueueSpec{{Name: "q", Rate: 1, Capacity: 1, To: "out"}},
+		Sinks:   []SinkSpec{{Name: "out"}},
+	}
+	n = run(t, top, warp.Config{LPs: 1, Sequential: true, Seed: 4}, 50000)
+	if s := n.Station("q"); !near(float64(s.Lost)/float64(s.Lost+s.Arrivals), 0.5, 0.05) || s.MeanQueue > 1e-9 {
+		t.Fatalf("M/M/1/1: %+v", s)
+	}
+}
+
+/*
+ * the statistics of the stations are computed from the committed events:
+ * with a router that counts the jobs in two stations, a state restored by
+ * the rollbacks, the parallel runs end with the statistics of the
+ * sequential one. The times have no ties, or the order of the events
+ * of the same time would depend on the kernel
+ */
+func TestRollback(t *testing.T) {
+	top := jacksonTopology()
+	top.Scale = 1e6 // no ties, the end time is close to the maximum warp.Time
+	top.Routers = append(top.Routers, RouterSpec{Name: "sq", To: []string{"q3", "q4"}, Policy: "shortest"})
+	top.Queues[1].To = "sq"
+	top.Queues = append(top.Queues, QueueSpec{Name: "q3", Rate: 1, To: "out"}, QueueSpec{Name: "q4", Rate: 1, To: "out"})
+
+	n, err := New(top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warptest.Compare(t, warp.Config{LPs: 4, Seed: 5, EndTime: n.Ticks(2000), Window: n.Ticks(5)},
+		[]warp.Partitioner{nil, warp.RoundRobinPartition{LPs: 4}},
+		func(cfg warp.Config) (*warp.Result, error) {
+			if n, err = New(top); err != nil {
+				return nil, err
+			}
+			return n.Run(cfg)
+		},
+		func() interface{} { return n.Stats() })
+	for _, s := range n.Stats() {
+		if s.Ties != 0 {
+			t.Fatalf("%s: %d ties, the order of the events depends on the kernel", s.Name, s.Ties)
+		}
+	}
+}
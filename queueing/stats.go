@@ -0,0 +1,98 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package queueing
+
+/*
+ * STATISTICS
+ *
+ * the statistics of a component are updated by Commit, that the kernel
+ * calls with the events of the component that can no longer be rolled
+ * back, in time order (see warp.Committer). A queue replays its arrivals
+ * and departures: the number of jobs in the station is enough for the
+ * time averages, as the servers are busy with the first Servers jobs, and
+ * a departure carries the arrival and service times of its job.
+ */
+
+import (
+	"github.com/jeffallen/go-warp/warp"
+)
+
+type Stats struct {
+	Name        string  `json:"name"`
+	Kind        string  `json:"kind"`         // source, queue, router or sink
+	Arrivals    int     `json:"arrivals"`     // jobs arrived (generated, for a source)
+	Departures  int     `json:"departures"`   // jobs served (absorbed, for a sink)
+	Lost        int     `json:"lost"`         // jobs arrived at a full queue
+	Utilization float64 `json:"utilization"`  // time average of the busy servers / servers
+	MeanJobs    float64 `json:"mean_jobs"`    // time average of the jobs in the station (L)
+	MeanQueue   float64 `json:"mean_queue"`   // time average of the waiting jobs (Lq)
+	MeanWait    float64 `json:"mean_wait"`    // of the served jobs, before the service (Wq)
+	MeanSojourn float64 `json:"mean_sojourn"` // of the served jobs (W); for a sink, since the creation of the jobs
+	Throughput  float64 `json:"throughput"`   // departures per time unit
+	Ties        int     `json:"ties"`         // events with the time of the previous one, their order depends on the kernel
+}
+
+/* the committed history of a component, it is not saved and restored with the state */
+type accumulator struct {
+	arrivals, departures, lost int
+	jobs                       int       // in the station
+	servers                    int       // of a queue, 0 for the other components
+	last                       warp.Time // of the last committed event
+	started                    bool      // an event has been committed
+	jobArea, busyArea          float64   // in jobs * ticks
+	wait, sojourn              float64   // sums, in time units
+	ties                       int
+}
+
+func (a *accumulator) advance(t warp.Time) {
+	if a.started && t == a.last {
+		a.ties++
+	}
+	a.area(t)
+	a.started = true
+}
+
+/* adds the time from the last event to t to the areas */
+func (a *accumulator) area(t warp.Time) {
+	busy := a.jobs
+	if busy > a.servers {
+		busy = a.servers
+	}
+	a.jobArea += float64(a.jobs) * float64(t-a.last)
+	a.busyArea += float64(busy) * float64(t-a.last)
+	a.last = t
+}
+
+/* the statistics over [0, end), the accumulator is not changed */
+func (a accumulator) stats(n *Network, end warp.Time, name, kind string) Stats {
+	s := Stats{Name: name, Kind: kind, Arrivals: a.arrivals, Departures: a.departures, Lost: a.lost, Ties: a.ties}
+	if end > a.last {
+		a.area(end)
+	}
+	if end <= 0 {
+		return s
+	}
+	T := float64(end)
+	s.MeanJobs = a.jobArea / T
+	s.MeanQueue = s.MeanJobs - a.busyArea/T
+	if a.servers > 0 {
+		s.Utilization = a.busyArea / T / float64(a.servers)
+	}
+	s.Throughput = float64(a.departures) / n.Units(end)
+	if a.departures > 0 {
+		s.MeanWait = a.wait / float64(a.departures)
+		s.MeanSojourn = a.sojourn / float64(a.departures)
+	}
+	return s
+}
@@ -0,0 +1,196 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package rng
+
+/*
+ * DISTRIBUTIONS
+ *
+ * every distribution consumes a fixed number of draws (calls of Float64),
+ * that depends only on its parameters (one draw, unless otherwise stated):
+ * the streams stay aligned when the same events are executed with
+ * different outcomes. The continuous distributions are obtained by
+ * inversion of the cumulative distribution function, the discrete ones by
+ * a search of the inverse.
+ */
+
+import (
+	"math"
+	"sort"
+)
+
+const POISSONMAX = 500.0 // above this mean the Poisson distribution is approximated by a normal one
+
+/* a discrete distribution over 0..n-1 given by its (cumulative) weights */
+type Empirical struct {
+	cdf []float64
+}
+
+/* weights[i] is the (non normalized) probability of i, the weights must not be negative */
+func NewEmpirical(weights []float64) *Empirical {
+	cdf := make([]float64, len(weights))
+	sum := 0.0
+	for i, w := range weights {
+		if w < 0 || math.IsNaN(w) {
+			panic("Negative weight.")
+		}
+		sum += w
+		cdf[i] = sum
+	}
+	if sum <= 0 {
+		panic("The weights must not be all zero.")
+	}
+	for i := range cdf {
+		cdf[i] /= sum
+	}
+	return &Empirical{cdf}
+}
+
+/* the Zipf distribution over 0..n-1: the probability of i is proportional to 1/(i+1)^s */
+func NewZipf(n int, s float64) *Empirical {
+	if n <= 0 || s < 0 {
+		panic("Invalid Zipf parameters.")
+	}
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = math.Pow(float64(i+1), -s)
+	}
+	return NewEmpirical(w)
+}
+
+/* the number of values of the distribution */
+func (e *Empirical) Len() int {
+	return len(e.cdf)
+}
+
+/* RandFloat generates a float64 in the range (0, 1) */
+func (r *Rand) RandFloat() float64 {
+	return r.Float64()
+}
+
+/* RandIntUniform generates an int32 with a uniform distribution over [min, max] */
+func (r *Rand) RandIntUniform(min int32, max int32) int32 {
+	if min > max {
+		return 0
+	}
+	return int32(r.Float64()*float64(int64(max)-int64(min)+1)) + min
+}
+
+/* RandUniform generates a float64 with a uniform distribution over [a, b) */
+func (r *Rand) RandUniform(a, b float64) float64 {
+	return a + (b-a)*r.Float64()
+}
+
+/* RandExponential generates a float64 with an exponential distribution of the given mean */
+func (r *Rand) RandExponential(mean float64) float64 {
+	return -mean * math.Log(r.Float64())
+}
+
+/* RandNormal generates a float64 with a normal distribution N(mu, sigma^2) */
+func (r *Rand) RandNormal(mu, sigma float64) float64 {
+	return mu + sigma*stdNormal(r.Float64())
+}
+
+/* RandLogNormal generates exp(X), where X has a normal distribution N(mu, sigma^2) */
+func (r *Rand) RandLogNormal(mu, sigma float64) float64 {
+	return math.Exp(r.RandNormal(mu, sigma))
+}
+
+/*
+ * RandPoisson generates an int with a Poisson distribution of mean lambda,
+ * if lambda > POISSONMAX the normal approximation is used
+ */
+func (r *Rand) RandPoisson(lambda float64) int {
+	if lambda < 0 {
+		panic("Negative Poisson mean.")
+	}
+	u := r.Float64()
+	if lambda > POISSONMAX {
+		k := math.Floor(lambda + math.Sqrt(lambda)*stdNormal(u) + 0.5)
+		return int(math.Max(k, 0))
+	}
+
+	k := 0
+	p := math.Exp(-lambda)
+	cdf := p
+	for u > cdf && p > 0 {
+		k++
+		p *= lambda / float64(k)
+		cdf += p
+	}
+	return k
+}
+
+/*
+ * RandGeometric generates the number of failures before the first success
+ * of independent trials with success probability p, in (0, 1]
+ */
+func (r *Rand) RandGeometric(p float64) int {
+	if p <= 0 || p > 1 {
+		panic("Invalid geometric probability.")
+	}
+	u := r.Float64()
+	if p == 1 {
+		return 0
+	}
+	return int(math.Floor(math.Log(u) / math.Log1p(-p)))
+}
+
+/*
+ * RandWeibull generates a float64 with a Weibull distribution of the given
+ * shape and scale, both positive
+ */
+func (r *Rand) RandWeibull(shape, scale float64) float64 {
+	if !(shape > 0 && scale > 0) {
+		panic("Invalid Weibull parameters.")
+	}
+	return scale * math.Pow(-math.Log(r.Float64()), 1/shape)
+}
+
+/*
+ * RandPareto generates a float64 with a Pareto distribution, minimum xm and
+ * index alpha, both positive
+ */
+func (r *Rand) RandPareto(xm, alpha float64) float64 {
+	if !(xm > 0 && alpha > 0) {
+		panic("Invalid Pareto parameters.")
+	}
+	return xm * math.Pow(r.Float64(), -1/alpha)
+}
+
+/*
+ * RandErlang generates the sum of k exponential variables with the given
+ * rate, it consumes k draws
+ */
+func (r *Rand) RandErlang(k int, rate float64) float64 {
+	s := 0.0
+	for i := 0; i < k; i++ {
+		s -= math.Log(r.Float64())
+	}
+	return s / rate
+}
+
+/* RandEmpirical generates an int with the distribution e */
+func (r *Rand) RandEmpirical(e *Empirical) int {
+	u := r.Float64()
+	i := sort.SearchFloat64s(e.cdf, u) // the first i with cdf[i] >= u
+	if i == len(e.cdf) {
+		i-- // rounding errors of the last cdf
+	}
+	return i
+}
+
+/* the inverse of the standard normal cumulative distribution function */
+func stdNormal(u float64) float64 {
+	return -math.Sqrt2 * math.Erfcinv(2*u)
+}
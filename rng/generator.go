@@ -0,0 +1,74 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package rng
+
+/*
+ * RANDOM NUMBER GENERATORS
+ *
+ * all the generators implement the Generator interface: every generator
+ * is a stream of a family (a seed and a stream index), the streams of the
+ * same family never overlap, and every stream is divided in substreams.
+ * The state of a generator is a slice of uint64, that can be saved and
+ * restored to checkpoint the simulation or to undo the draws of an event.
+ * Rand adds the distributions (see dist.go) to any generator.
+ */
+
+import (
+	"errors"
+)
+
+type Generator interface {
+	Uint64() uint64                  // 64 random bits
+	Float64() float64                // uniform in (0, 1), both excluded
+	NextSubstream()                  // moves to the start of the next substream
+	AppendState(s []uint64) []uint64 // appends the state of the generator to s
+	SetState(s []uint64) error       // restores a state returned by AppendState
+}
+
+/* returns the first n streams of the family with the given seed */
+type Source func(seed int64, n int) []Generator
+
+type Rand struct {
+	Generator
+}
+
+var errState = errors.New("rng: invalid state")
+
+func New(g Generator) *Rand {
+	return &Rand{g}
+}
+
+/* wraps the generators returned by a Source */
+func NewStreams(src Source, seed int64, n int) []*Rand {
+	g := src(seed, n)
+	ret := make([]*Rand, len(g))
+	for i := range g {
+		ret[i] = New(g[i])
+	}
+	return ret
+}
+
+/* the splitmix64 generator, used to initialize the state of the other generators */
+func splitmix(x *uint64) uint64 {
+	*x += 0x9e3779b97f4a7c15
+	z := *x
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+/* a float64 in (0, 1) from the 53 high bits of x */
+func toFloat(x uint64) float64 {
+	return (float64(x>>11) + 0.5) / (1 << 53)
+}
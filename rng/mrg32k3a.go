@@ -0,0 +1,189 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package rng
+
+/*
+ * MRG32k3a
+ *
+ * the combined multiple recursive generator of L'Ecuyer, with period about
+ * 2^191: the streams start 2^127 draws apart, the substreams 2^76 draws
+ * apart, as in RngStreams. The jumps are computed by powers of the
+ * transition matrices of the two components.
+ */
+
+import (
+	"math/bits"
+)
+
+const (
+	m1   = 4294967087
+	m2   = 4294944443
+	a12  = 1403580
+	a13n = 810728
+	a21  = 527612
+	a23n = 1370589
+	norm = 1.0 / (m1 + 1)
+)
+
+type matrix [3][3]uint64
+
+type MRG32k3a struct {
+	s   [6]uint64 // current state, the first 3 values modulo m1, the others modulo m2
+	sub [6]uint64 // start of the current substream
+}
+
+var (
+	trans1 = matrix{{0, 1, 0}, {0, 0, 1}, {m1 - a13n, a12, 0}}
+	trans2 = matrix{{0, 1, 0}, {0, 0, 1}, {m2 - a23n, 0, a21}}
+
+	stream1, stream2 = pow2(trans1, 127, m1), pow2(trans2, 127, m2)
+	sub1, sub2       = pow2(trans1, 76, m1), pow2(trans2, 76, m2)
+)
+
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+func (a *matrix) mul(b *matrix, m uint64) matrix {
+	var c matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				c[i][j] = (c[i][j] + mulMod(a[i][k], b[k][j], m)) % m
+			}
+		}
+	}
+	return c
+}
+
+/* a^(2^e) */
+func pow2(a matrix, e int, m uint64) matrix {
+	for i := 0; i < e; i++ {
+		a = a.mul(&a, m)
+	}
+	return a
+}
+
+/* a^e */
+func pow(a matrix, e uint64, m uint64) matrix {
+	r := matrix{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = r.mul(&a, m)
+		}
+		a = a.mul(&a, m)
+	}
+	return r
+}
+
+func (a *matrix) apply(v []uint64, m uint64) {
+	var r [3]uint64
+	for i := 0; i < 3; i++ {
+		for k := 0; k < 3; k++ {
+			r[i] = (r[i] + mulMod(a[i][k], v[k], m)) % m
+		}
+	}
+	copy(v, r[:])
+}
+
+/* the stream i of the family with the given seed */
+func NewMRG32k3a(seed int64, i int) *MRG32k3a {
+	g := new(MRG32k3a)
+	x := uint64(seed)
+	for k := range g.s {
+		m := uint64(m1)
+		if k >= 3 {
+			m = m2
+		}
+		g.s[k] = splitmix(&x) % m
+	}
+	if g.s[0]|g.s[1]|g.s[2] == 0 {
+		g.s[0] = 1
+	}
+	if g.s[3]|g.s[4]|g.s[5] == 0 {
+		g.s[3] = 1
+	}
+
+	p1, p2 := pow(stream1, uint64(i), m1), pow(stream2, uint64(i), m2)
+	p1.apply(g.s[:3], m1)
+	p2.apply(g.s[3:], m2)
+	g.sub = g.s
+	return g
+}
+
+/*
+ * the first n streams of the family, it is a Source: every stream is the
+ * previous one after a jump, so that many streams (one per entity) are cheap
+ */
+func MRG32k3aStreams(seed int64, n int) []Generator {
+	ret := make([]Generator, n)
+	if n == 0 {
+		return ret
+	}
+	prev := NewMRG32k3a(seed, 0)
+	ret[0] = prev
+	for i := 1; i < n; i++ {
+		g := &MRG32k3a{s: prev.sub}
+		stream1.apply(g.s[:3], m1)
+		stream2.apply(g.s[3:], m2)
+		g.sub = g.s
+		ret[i], prev = g, g
+	}
+	return ret
+}
+
+func (g *MRG32k3a) next() uint64 {
+	s := &g.s
+
+	p1 := (a12*s[1] + m1 - a13n*s[0]%m1) % m1
+	s[0], s[1], s[2] = s[1], s[2], p1
+
+	p2 := (a21*s[5] + m2 - a23n*s[3]%m2) % m2
+	s[3], s[4], s[5] = s[4], s[5], p2
+
+	if p1 > p2 {
+		return p1 - p2
+	}
+	return p1 - p2 + m1
+}
+
+/* the output is in [1, m1], the values are not exactly uniform on 64 bits */
+func (g *MRG32k3a) Uint64() uint64 {
+	return g.next()<<32 ^ g.next()
+}
+
+func (g *MRG32k3a) Float64() float64 {
+	return float64(g.next()) * norm
+}
+
+func (g *MRG32k3a) NextSubstream() {
+	sub1.apply(g.sub[:3], m1)
+	sub2.apply(g.sub[3:], m2)
+	g.s = g.sub
+}
+
+func (g *MRG32k3a) AppendState(s []uint64) []uint64 {
+	s = append(s, g.s[:]...)
+	return append(s, g.sub[:]...)
+}
+
+func (g *MRG32k3a) SetState(s []uint64) error {
+	if len(s) != 12 {
+		return errState
+	}
+	copy(g.s[:], s)
+	copy(g.sub[:], s[6:])
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package rng
+
+/*
+ * PCG32
+ *
+ * the PCG generator of O'Neill (XSH RR output on a 64 bit LCG): every
+ * stream has its own increment, so the family has 2^63 streams of period
+ * 2^64, divided in substreams of 2^48 draws.
+ */
+
+import (
+	"math/bits"
+)
+
+const (
+	pcgMult   = 6364136223846793005
+	PCGSUBLEN = 1 << 48 // outputs of 32 bits of a substream
+)
+
+type PCG struct {
+	state, inc uint64
+	sub        uint64 // state at the start of the current substream
+}
+
+func NewPCG(seed int64, i int) *PCG {
+	g := &PCG{inc: uint64(i)<<1 | 1}
+	g.next()
+	g.state += uint64(seed)
+	g.next()
+	g.sub = g.state
+	return g
+}
+
+/* the first n streams of the family, it is a Source */
+func PCGStreams(seed int64, n int) []Generator {
+	ret := make([]Generator, n)
+	for i := range ret {
+		ret[i] = NewPCG(seed, i)
+	}
+	return ret
+}
+
+func (g *PCG) next() uint32 {
+	old := g.state
+	g.state = old*pcgMult + g.inc
+	x := uint32(((old >> 18) ^ old) >> 27)
+	return bits.RotateLeft32(x, -int(old>>59))
+}
+
+/* moves the LCG of a state delta steps ahead, in O(log delta) */
+func (g *PCG) advance(state, delta uint64) uint64 {
+	accMult, accPlus := uint64(1), uint64(0)
+	curMult, curPlus := uint64(pcgMult), g.inc
+	for ; delta > 0; delta >>= 1 {
+		if delta&1 == 1 {
+			accMult *= curMult
+			accPlus = accPlus*curMult + curPlus
+		}
+		curPlus = (curMult + 1) * curPlus
+		curMult *= curMult
+	}
+	return accMult*state + accPlus
+}
+
+/* moves the generator n outputs of 32 bits ahead, or back if n is negative */
+func (g *PCG) Jump(n int64) {
+	g.state = g.advance(g.state, uint64(n)) // the period is 2^64
+}
+
+func (g *PCG) Uint64() uint64 {
+	return uint64(g.next())<<32 | uint64(g.next())
+}
+
+func (g *PCG) Float64() float64 {
+	return toFloat(g.Uint64())
+}
+
+func (g *PCG) NextSubstream() {
+	g.sub = g.advance(g.sub, PCGSUBLEN)
+	g.state = g.sub
+}
+
+func (g *PCG) AppendState(s []uint64) []uint64 {
+	return append(s, g.state, g.inc, g.sub)
+}
+
+func (g *PCG) SetState(s []uint64) error {
+	if len(s) != 3 || s[1]&1 == 0 {
+		return errState
+	}
+	g.state, g.inc, g.sub = s[0], s[1], s[2]
+	return nil
+}
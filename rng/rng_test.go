@@ -0,0 +1,186 @@
+package rng
+
+import (
+	"math"
+	"testing"
+)
+
+var sources = []struct {
+	name string
+	src  Source
+}{
+	{"MRG32k3a", MRG32k3aStreams},
+	{"PCG", PCGStreams},
+	{"Xoshiro", XoshiroStreams},
+}
+
+/* the jump matrices of RngStreams */
+func TestMRG32k3aMatrices(t *testing.T) {
+	want := []struct {
+		name string
+		got  matrix
+		m    matrix
+	}{
+		{"A1p127", stream1, matrix{{2427906178, 3580155704, 949770784}, {226153695, 1230515664, 3580155704}, {1988835001, 986791581, 1230515664}}},
+		{"A2p127", stream2, matrix{{1464411153, 277697599, 1610723613}, {32183930, 1464411153, 1022607788}, {2824425944, 32183930, 2093834863}}},
+		{"A1p76", sub1, matrix{{82758667, 1871391091, 4127413238}, {3672831523, 69195019, 1871391091}, {3672091415, 3528743235, 69195019}}},
+		{"A2p76", sub2, matrix{{1511326704, 3759209742, 1610795712}, {4292754251, 1511326704, 3889917532}, {3859662829, 4292754251, 3708466080}}},
+	}
+	for _, w := range want {
+		if w.got != w.m {
+			t.Errorf("%s: %v, want %v", w.name, w.got, w.m)
+		}
+	}
+
+	/* the first number with the default seed of RngStreams */
+	g := new(MRG32k3a)
+	g.SetState([]uint64{12345, 12345, 12345, 12345, 12345, 12345, 12345, 12345, 12345, 12345, 12345, 12345})
+	if f := g.Float64(); math.Abs(f-0.1270111220) > 1e-9 {
+		t.Fatal("first number", f)
+	}
+}
+
+/* the first numbers of the pcg32 demo, seed 42 and sequence 54 */
+func TestPCGReference(t *testing.T) {
+	g := NewPCG(42, 54)
+	for _, want := range []uint32{0xa15c02b7, 0x7b47f409, 0xba1d3330, 0x83d2f293, 0xbfa4784b, 0xcbed606e} {
+		if x := g.next(); x != want {
+			t.Fatalf("%#x, want %#x", x, want)
+		}
+	}
+
+	j := NewPCG(42, 54)
+	j.Jump(6)
+	if j.state != g.state {
+		t.Fatal("Jump(6) is not 6 draws ahead")
+	}
+	j.Jump(-6)
+	if x := j.next(); x != 0xa15c02b7 {
+		t.Fatalf("Jump(-6): %#x", x)
+	}
+}
+
+/* a jump commutes with the steps of the generator */
+func TestXoshiroJump(t *testing.T) {
+	a := newXoshiro(9)
+	b := *a
+	stepXoshiro(&a.s)
+	a.s = jumpXoshiro(a.s, &xoshiroJump)
+	b.s = jumpXoshiro(b.s, &xoshiroJump)
+	stepXoshiro(&b.s)
+	if a.s != b.s {
+		t.Fatal("the jump does not commute with a step")
+	}
+	if s := XoshiroStreams(9, 3)[2].(*Xoshiro); s.s != NewXoshiro(9, 2).s {
+		t.Fatal("XoshiroStreams and NewXoshiro differ")
+	}
+}
+
+func TestRange(t *testing.T) {
+	for _, s := range sources {
+		r := New(s.src(1, 1)[0])
+		tot := int64(0)
+		for i := 0; i < 1e6; i++ {
+			if f := r.Float64(); f <= 0 || f >= 1 {
+				t.Fatal(s.name, "out of limits: ", f)
+			}
+			x := r.RandIntUniform(6, 10)
+			if x < 6 || x > 10 {
+				t.Fatal(s.name, "out of limits: ", x)
+			}
+			tot += int64(x)
+		}
+		if avg := float64(tot) / 1e6; math.Abs(avg-8) > 0.005 {
+			t.Error(s.name, "mean too far from expected:", avg)
+		}
+	}
+}
+
+/* the numbers are spread uniformly in 100 bins, and two successive numbers are not correlated */
+func TestUniformity(t *testing.T) {
+	const n, bins = 1000000, 100
+	for _, src := range sources {
+		g := src.src(7, 1)[0]
+		count := make([]int, bins)
+		var sxy, sx, sxx float64
+		prev := g.Float64()
+		for i := 0; i < n; i++ {
+			x := g.Float64()
+			count[int(x*bins)]++
+			sxy += prev * x
+			sx += x
+			sxx += x * x
+			prev = x
+		}
+		chi2 := 0.0
+		for _, c := range count {
+			d := float64(c) - n/bins
+			chi2 += d * d / (n / bins)
+		}
+		if chi2 > 99+4.5*math.Sqrt(99)+10 {
+			t.Errorf("%s: chi-square %g", src.name, chi2)
+		}
+		m := sx / n
+		if corr := (sxy/n - m*m) / (sxx/n - m*m); math.Abs(corr) > 0.005 {
+			t.Errorf("%s: serial correlation %g", src.name, corr)
+		}
+	}
+}
+
+func TestStreams(t *testing.T) {
+	for _, src := range sources {
+		seen := make(map[uint64]int)
+		for i, g := range src.src(3, 200) {
+			for k := 0; k < 50; k++ {
+				x := g.Uint64()
+				if j, ok := seen[x]; ok {
+					t.Fatalf("%s: streams %d and %d overlap", src.name, j, i)
+				}
+				seen[x] = i
+			}
+		}
+	}
+	/* the streams computed one after the other are the ones computed directly */
+	for _, seed := range []int64{0, 3, -7} {
+		for i, g := range MRG32k3aStreams(seed, 20) {
+			if *g.(*MRG32k3a) != *NewMRG32k3a(seed, i) {
+				t.Fatalf("MRG32k3a: stream %d of seed %d differs", i, seed)
+			}
+		}
+	}
+	if len(MRG32k3aStreams(3, 0)) != 0 {
+		t.Fatal("MRG32k3a: streams with n = 0")
+	}
+}
+
+/* a saved state produces the same numbers, also after a change of substream */
+func TestState(t *testing.T) {
+	for _, src := range sources {
+		g, h := src.src(5, 2)[1], src.src(5, 2)[1]
+		for i := 0; i < 10; i++ {
+			g.Uint64()
+		}
+		s := g.AppendState(nil)
+		a := []uint64{g.Uint64(), g.Uint64()}
+		g.NextSubstream()
+		a = append(a, g.Uint64())
+
+		if err := h.SetState(s); err != nil {
+			t.Fatal(src.name, err)
+		}
+		b := []uint64{h.Uint64(), h.Uint64()}
+		h.NextSubstream()
+		b = append(b, h.Uint64())
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("%s: number %d is %d, want %d", src.name, i, b[i], a[i])
+			}
+		}
+		if a[2] == a[0] || a[2] == a[1] {
+			t.Fatalf("%s: the substream repeats the stream", src.name)
+		}
+		if err := h.SetState(s[1:]); err == nil {
+			t.Fatalf("%s: invalid state accepted", src.name)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package rng
+
+/*
+ * XOSHIRO256**
+ *
+ * the generator of Blackman and Vigna, with period 2^256 - 1: the streams
+ * start 2^192 draws apart (long jump), the substreams 2^128 draws apart
+ * (jump). A jump costs 256 draws, so the stream i is computed with i long
+ * jumps: XoshiroStreams computes all the streams of a family in one pass.
+ */
+
+import (
+	"math/bits"
+)
+
+type Xoshiro struct {
+	s   [4]uint64
+	sub [4]uint64 // start of the current substream
+}
+
+var (
+	xoshiroJump     = [4]uint64{0x180ec6d33cfd0aba, 0xd5a61266f0c9392c, 0xa9582618e03fc9aa, 0x39abdc4529b1661c}
+	xoshiroLongJump = [4]uint64{0x76e15d3efefdcbbf, 0xc5004e441c522fb3, 0x77710069854ee241, 0x39109bb02acbe635}
+)
+
+func newXoshiro(seed int64) *Xoshiro {
+	g := new(Xoshiro)
+	x := uint64(seed)
+	for k := range g.s {
+		g.s[k] = splitmix(&x)
+	}
+	if g.s[0]|g.s[1]|g.s[2]|g.s[3] == 0 {
+		g.s[0] = 1
+	}
+	g.sub = g.s
+	return g
+}
+
+func NewXoshiro(seed int64, i int) *Xoshiro {
+	g := newXoshiro(seed)
+	for k := 0; k < i; k++ {
+		g.s = jumpXoshiro(g.s, &xoshiroLongJump)
+	}
+	g.sub = g.s
+	return g
+}
+
+/* the first n streams of the family, it is a Source */
+func XoshiroStreams(seed int64, n int) []Generator {
+	ret := make([]Generator, n)
+	g := newXoshiro(seed)
+	for i := range ret {
+		c := *g
+		ret[i] = &c
+		g.s = jumpXoshiro(g.s, &xoshiroLongJump)
+		g.sub = g.s
+	}
+	return ret
+}
+
+func stepXoshiro(s *[4]uint64) uint64 {
+	ret := bits.RotateLeft64(s[1]*5, 7) * 9
+	t := s[1] << 17
+	s[2] ^= s[0]
+	s[3] ^= s[1]
+	s[1] ^= s[2]
+	s[0] ^= s[3]
+	s[2] ^= t
+	s[3] = bits.RotateLeft64(s[3], 45)
+	return ret
+}
+
+/* the state after the jump given by the polynomial p */
+func jumpXoshiro(s [4]uint64, p *[4]uint64) [4]uint64 {
+	var r [4]uint64
+	for _, w := range p {
+		for b := uint(0); b < 64; b++ {
+			if w&(1<<b) != 0 {
+				r[0] ^= s[0]
+				r[1] ^= s[1]
+				r[2] ^= s[2]
+				r[3] ^= s[3]
+			}
+			stepXoshiro(&s)
+		}
+	}
+	return r
+}
+
+func (g *Xoshiro) Uint64() uint64 {
+	return stepXoshiro(&g.s)
+}
+
+func (g *Xoshiro) Float64() float64 {
+	return toFloat(stepXoshiro(&g.s))
+}
+
+func (g *Xoshiro) NextSubstream() {
+	g.sub = jumpXoshiro(g.sub, &xoshiroJump)
+	g.s = g.sub
+}
+
+func (g *Xoshiro) AppendState(s []uint64) []uint64 {
+	s = append(s, g.s[:]...)
+	return append(s, g.sub[:]...)
+}
+
+func (g *Xoshiro) SetState(s []uint64) error {
+	if len(s) != 8 || s[0]|s[1]|s[2]|s[3] == 0 {
+		return errState
+	}
+	copy(g.s[:], s)
+	copy(g.sub[:], s[4:])
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+/*
+ * tracediff sorts two committed event traces in the canonical order and
+ * reports the first divergence between them. The trace format is chosen
+ * by the file extension (.csv, .json/.jsonl, otherwise binary)
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jeffallen/go-warp/warp"
+	"io"
+	"os"
+)
+
+const usage = "tracediff [-id] [-context N] TRACE1 TRACE2"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout))
+}
+
+/* returns the exit status: 0 if the traces are equal, 1 if they differ, 2 on errors */
+func run(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("tracediff", flag.ContinueOnError)
+	fs.SetOutput(out)
+	id := fs.Bool("id", false, "compare also the event identifiers, that are assigned by the kernel and differ between runs with rollbacks")
+	context := fs.Int("context", 3, "number of records printed around the divergence")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		fmt.Fprintln(out, usage)
+		return 2
+	}
+
+	a, err := warp.ReadTraceFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(out, "tracediff, error reading", fs.Arg(0)+":", err)
+		return 2
+	}
+	b, err := warp.ReadTraceFile(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(out, "tracediff, error reading", fs.Arg(1)+":", err)
+		return 2
+	}
+
+	warp.SortTrace(a, !*id)
+	warp.SortTrace(b, !*id)
+
+	i := warp.DiffTrace(a, b, !*id)
+	if i < 0 {
+		fmt.Fprintln(out, "the traces are equal:", len(a), "committed events")
+		return 0
+	}
+
+	fmt.Fprintf(out, "the traces diverge at record %d (%d vs %d committed events)\n", i, len(a), len(b))
+	fmt.Fprintln(out, "<", fs.Arg(0))
+	printAround(out, a, i, *context)
+	fmt.Fprintln(out, ">", fs.Arg(1))
+	printAround(out, b, i, *context)
+	return 1
+}
+
+func printAround(out io.Writer, trace []warp.TraceRecord, i, context int) {
+	from := i - context
+	if from < 0 {
+		from = 0
+	}
+	to := i + context + 1
+	if to > len(trace) {
+		to = len(trace)
+	}
+	for j := from; j < to; j++ {
+		mark := " "
+		if j == i {
+			mark = "*"
+		}
+		r := &trace[j]
+		fmt.Fprintf(out, "%s %8d  lp=%d time=%d id=%d sender=%d from=%d to=%d flag=%d data=%x\n", mark, j,
+			r.LP, r.Ev.Time, r.Ev.Id, r.Ev.Sender, r.Ev.Type.From, r.Ev.Type.To, r.Ev.Type.Flag, r.Ev.Type.Data)
+	}
+	if i >= len(trace) {
+		fmt.Fprintln(out, "  (end of trace)")
+	}
+}
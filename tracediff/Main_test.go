@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"github.com/jeffallen/go-warp/warp"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTrace(t *testing.T, path string, trace []warp.TraceRecord) string {
+	tw, err := warp.CreateTraceFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range trace {
+		tw.Write(&trace[i])
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTraceDiff(t *testing.T) {
+	dir := t.TempDir()
+	var a []warp.TraceRecord
+	for i := 0; i < 10; i++ {
+		a = append(a, warp.TraceRecord{LP: warp.Pid(i % 3), Ev: warp.Event{Id: int64(i + 1), Time: warp.Time(i / 2), Type: warp.Info{From: i, To: i + 1, Flag: int32(i)}}})
+	}
+	/* the same events in another order and with other ids */
+	b := make([]warp.TraceRecord, len(a))
+	for i := range a {
+		b[len(a)-1-i] = a[i]
+		b[len(a)-1-i].Ev.Id += 100
+	}
+	c := append([]warp.TraceRecord(nil), a...)
+	c[6].Ev.Type.Flag = 99
+
+	pa := writeTrace(t, filepath.Join(dir, "a.bin"), a)
+	pb := writeTrace(t, filepath.Join(dir, "b.csv"), b)
+	pc := writeTrace(t, filepath.Join(dir, "c.jsonl"), c)
+
+	for _, tc := range []struct {
+		args   []string
+		status int
+		out    string
+	}{
+		{[]string{pa, pb}, 0, "the traces are equal: 10 committed events"},
+		{[]string{"-id", pa, pb}, 1, "the traces diverge at record 0 (10 vs 10 committed events)"},
+		{[]string{pa, pc}, 1, "the traces diverge at record 6 (10 vs 10 committed events)"},
+		{[]string{"-context", "0", pc, pa}, 1, "*        6  lp=0 time=3 id=7 sender=0 from=6 to=7 flag=99"},
+		{[]string{pa, filepath.Join(dir, "missing.bin")}, 2, "tracediff, error reading"},
+		{[]string{pa}, 2, usage},
+	} {
+		var out bytes.Buffer
+		if s := run(tc.args, &out); s != tc.status || !strings.Contains(out.String(), tc.out) {
+			t.Errorf("tracediff %v: status %d, output:\n%s", tc.args, s, out.String())
+		}
+	}
+}
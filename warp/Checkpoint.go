@@ -0,0 +1,312 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * CHECKPOINT AND RESTART
+ *
+ * the simulation is executed up to a Horizon: the LPs do not process the
+ * events with time >= Horizon and the run terminates as it does at
+ * EndTime. At that point all the processed events are committed and the
+ * GVT is equal to the Horizon, so the pending events, the model state and
+ * the counters of every LP form a consistent cut that can be written to
+ * disk. Resume moves the Horizon forward on the running kernel, Restart
+ * rebuilds the kernel from a checkpoint: both continue in the same way.
+ */
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	CKPTVERSION = 1 // version of the checkpoint format
+	ckptMagic   = "GOWARPCK"
+)
+
+/*
+ * implemented by the models that keep state across the events, the saved
+ * state must include the random number generators of the model (the
+ * random streams of the kernel are saved in the checkpoint)
+ */
+type Checkpointer interface {
+	SaveModel() ([]byte, error) // state shared by all the LPs
+	RestoreModel(b []byte) error
+	SaveLP(l *LocalData) ([]byte, error) // committed state of a single LP
+	RestoreLP(l *LocalData, b []byte) error
+}
+
+type LPCheckpoint struct {
+	LP          Pid
+	SimTime     Time
+	NProcessed  int
+	Rollbacks   int
+	Stats       LPStats
+	Events      []Event        // pending events, in heap order
+	Retractions []TimedMessage // the cancelled events after the cut, see Schedule.go
+	LastId      int64          // sequence number of the last event created by the LP
+	State       []byte         // produced by Checkpointer.SaveLP
+}
+
+type Checkpoint struct {
+	Version int
+	Lpnum   int
+	EndTime Time
+	Gvt     Time // all the events with time < Gvt have been committed
+	NGvt    int
+	Model   []byte // produced by Checkpointer.SaveModel
+	LPs     []LPCheckpoint
+
+	Streams   [][]uint64     // the states of the random streams, see Random.go
+	Partition TablePartition // the LP of every entity, nil if the model has no entities
+}
+
+var (
+	Horizon Time // the events with time >= Horizon are not processed
+
+	ckpt Checkpointer
+)
+
+func SetCheckpointer(c Checkpointer) {
+	ckpt = c
+}
+
+/*
+ * continues a simulation stopped at the Horizon up to time t (at most
+ * EndTime), Simulate must then be called again for every LP
+ */
+func Resume(t Time) {
+	quiesce()
+	if t > EndTime {
+		t = EndTime
+	}
+	Horizon = t
+	for i, data := range lpData {
+		if data != nil {
+			setState(Pid(i), LPRUNNING)
+		}
+	}
+}
+
+/*
+ * brings the stopped kernel in a clean state: the messages left in the
+ * channels are consumed, the processed events are committed (the
+ * retractions of the events before the cut are dropped) and all the LPs
+ * agree on the GVT, that is the Horizon if the run has reached it
+ */
+func quiesce() {
+	for i := range lpData {
+		for m := Receive(Pid(i)); m != nil; m = Receive(Pid(i)) {
+			if m.Ev.Time < 0 {
+				continue // control messages of the terminated run
+			}
+			data := lpData[m.Receiver]
+			if e := m.Ev.Type.To; entities != nil && e >= 0 && e < len(entities) {
+				data = lpData[partition.LP(e)] // the receiver entity may have been migrated
+			}
+			if data == nil {
+				continue
+			}
+			if m.Ev.Type.Flag == ANTIMSG {
+				data.FutureEvents.Delete(CreateEvent(-m.Ev.Id, m.Ev.Time, Info{}))
+			} else {
+				data.insertEvent(&m.Ev)
+			}
+		}
+	}
+
+	cut := Horizon
+	for _, data := range lpData {
+		if data == nil {
+			continue
+		}
+		if t := data.FutureEvents.GetMinTime(); t != NOTIME && t < cut {
+			cut = t
+		}
+	}
+
+	gvtResume(cut)
+	for _, data := range lpData {
+		if data == nil {
+			continue
+		}
+		commitEvents(MAXTIME, data)
+		lpCommitted[data.IndexLP] = int32(cut - 1) // the events of the next slice are not committed yet
+		fossilRetractions(cut-1, data.Retractions) // only the events after the cut can be cancelled again
+		data.ProcessedEvents.Init()
+		data.MsgSent.Init()
+		data.StateLog.Init()
+		data.DrawLog.Init()
+		data.OutgoingMsg.Init()
+		data.Acked.Init()
+		data.AntiMsg2Annihilate.Init()
+		data.GvtFlag = false
+		data.Gvt = cut
+	}
+}
+
+/* takes the checkpoint of a simulation stopped at the Horizon */
+func TakeCheckpoint() (*Checkpoint, error) {
+	var err error
+
+	quiesce()
+
+	c := &Checkpoint{Version: CKPTVERSION, Lpnum: Lpnum, EndTime: EndTime, Gvt: Horizon, NGvt: N_gvt,
+		Streams: saveStreams()}
+	if entities != nil {
+		c.Partition = make(TablePartition, len(entities))
+		for e := range c.Partition {
+			c.Partition[e] = partition.LP(e)
+		}
+	}
+	if ckpt != nil {
+		if c.Model, err = ckpt.SaveModel(); err != nil {
+			return nil, err
+		}
+	}
+	for i, data := range lpData {
+		if data == nil {
+			continue
+		}
+		lp := LPCheckpoint{LP: Pid(i), SimTime: data.SimTime, NProcessed: data.N_PROCESSED,
+			Rollbacks: N_rollback[i], Stats: data.Stats, Events: data.FutureEvents.Events(), LastId: data.lastId}
+		for el := data.Retractions.Front(); el != nil; el = el.Next() {
+			lp.Retractions = append(lp.Retractions, el.Value.(TimedMessage))
+		}
+		if ckpt != nil {
+			if lp.State, err = ckpt.SaveLP(data); err != nil {
+				return nil, fmt.Errorf("GO-WARP: saving the state of LP %d: %v", i, err)
+			}
+		}
+		c.LPs = append(c.LPs, lp)
+	}
+	return c, nil
+}
+
+/*
+ * rebuilds the kernel (as SimSetup and SimInitialize do) from a checkpoint
+ * and returns the local areas of the LPs, ready for Simulate once the
+ * entities and the random streams are set. The entities keep the partition
+ * of the checkpoint (with the migrations done before it), see SetEntities
+ */
+func Restart(c *Checkpoint, f func(ev *Event, l *LocalData)) ([]*LocalData, error) {
+	if c.Version != CKPTVERSION {
+		return nil, fmt.Errorf("GO-WARP: checkpoint version %d, expected %d", c.Version, CKPTVERSION)
+	}
+
+	SimSetup(c.Lpnum, c.EndTime, f)
+	Horizon = c.Gvt
+	quiesce()
+	N_gvt = c.NGvt
+	ckptStreams = c.Streams // restored by SetStreams
+	ckptPartition = c.Partition
+
+	if ckpt != nil {
+		if err := ckpt.RestoreModel(c.Model); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := make([]*LocalData, c.Lpnum)
+	for i := range c.LPs {
+		lp := &c.LPs[i]
+		if int(lp.LP) >= c.Lpnum {
+			return nil, fmt.Errorf("GO-WARP: checkpoint of LP %d, the simulation has %d LPs", lp.LP, c.Lpnum)
+		}
+		data := SimInitialize(lp.LP)
+		data.SimTime = lp.SimTime
+		data.Gvt = c.Gvt
+		data.N_PROCESSED = lp.NProcessed
+		data.Stats = lp.Stats
+		data.lastId = lp.LastId
+		N_rollback[lp.LP] = lp.Rollbacks
+		for j := range lp.Events {
+			data.insertEvent(&lp.Events[j])
+		}
+		for _, tm := range lp.Retractions {
+			data.Retractions.PushBack(tm)
+		}
+		if ckpt != nil {
+			if err := ckpt.RestoreLP(data, lp.State); err != nil {
+				return nil, fmt.Errorf("GO-WARP: restoring the state of LP %d: %v", lp.LP, err)
+			}
+		}
+		ret[lp.LP] = data
+	}
+	Horizon = EndTime
+	return ret, nil
+}
+
+/* the format is: magic string, version (uint32, little-endian), gob encoded Checkpoint */
+func (c *Checkpoint) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, ckptMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(c.Version)); err != nil {
+		return err
+	}
+	return gob.NewEncoder(w).Encode(c)
+}
+
+func ReadCheckpoint(r io.Reader) (*Checkpoint, error) {
+	var version uint32
+
+	magic := make([]byte, len(ckptMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != ckptMagic {
+		return nil, errors.New("GO-WARP: not a checkpoint file")
+	}
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != CKPTVERSION {
+		return nil, fmt.Errorf("GO-WARP: checkpoint version %d, expected %d", version, CKPTVERSION)
+	}
+
+	c := new(Checkpoint)
+	if err := gob.NewDecoder(r).Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+/* the checkpoint is written to a temporary file that is then renamed */
+func WriteCheckpointFile(c *Checkpoint, path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = c.Write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func ReadCheckpointFile(path string) (*Checkpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCheckpoint(f)
+}
@@ -0,0 +1,208 @@
+package warp
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"testing"
+	"time"
+)
+
+/* saves the states of all the testCounter entities with the model */
+type testCheckpointer struct {
+	counters []testCounter
+}
+
+func (c *testCheckpointer) SaveModel() ([]byte, error) {
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(c.counters)
+	return b.Bytes(), err
+}
+
+func (c *testCheckpointer) RestoreModel(b []byte) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(&c.counters)
+}
+
+func (c *testCheckpointer) SaveLP(l *LocalData) ([]byte, error) { return nil, nil }
+
+func (c *testCheckpointer) RestoreLP(l *LocalData, b []byte) error { return nil }
+
+/*
+ * runs the testCounter entities as runEntitiesConfig does, in slices of
+ * every time units with a rebalancing after each slice if cfg.Policy is
+ * set. At time at the simulation is checkpointed and restarted on new
+ * entities, that are returned with the trace
+ */
+func runRestarted(t *testing.T, cfg Config, every, at Time) ([]TraceRecord, []testCounter) {
+	stop := startTrace(t)
+	defer SetTrace(nil)
+	defer SetCheckpointer(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cp := &testCheckpointer{counters: make([]testCounter, testEntities)}
+	SetCheckpointer(cp)
+	Window, GvtThreshold, Lookahead = 0, TOOLARGE, 0
+	SimSetup(cfg.LPs, 200, EntityManager)
+	SetEntities(testCounters(cp.counters), cfg.Partition)
+	SetStreams(0, nil)
+	lps := make([]*LocalData, cfg.LPs)
+	for i := range lps {
+		lps[i] = SimInitialize(Pid(i))
+		testEntityInit(24)(lps[i])
+	}
+
+	for h := every; ; h += every {
+		Resume(h)
+		if RunLPs(ctx, lps).Cancelled {
+			t.Fatalf("restarted at %d: the simulation did not terminate", at)
+		}
+		if Horizon >= EndTime {
+			break
+		}
+		if cfg.Policy != nil {
+			if _, err := Rebalance(cfg.Policy); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if h != at {
+			continue
+		}
+
+		c, err := TakeCheckpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := c.Write(&b); err != nil {
+			t.Fatal(err)
+		}
+		if c, err = ReadCheckpoint(&b); err != nil {
+			t.Fatal(err)
+		}
+		owners := make(TablePartition, testEntities)
+		for e := range owners {
+			owners[e] = EntityLP(e)
+		}
+
+		cp.counters = make([]testCounter, testEntities) // the entities of a new process
+		if lps, err = Restart(c, EntityManager); err != nil {
+			t.Fatal(err)
+		}
+		SetEntities(testCounters(cp.counters), nil)
+		SetStreams(0, nil)
+		for e := range owners {
+			if EntityLP(e) != owners[e] {
+				t.Fatalf("restarted at %d: entity %d owned by LP %d, it was LP %d", at, e, EntityLP(e), owners[e])
+			}
+		}
+	}
+
+	return stop(), cp.counters
+}
+
+/* a run restarted from a checkpoint commits the events and reaches the states of an uninterrupted run */
+func TestRestart(t *testing.T) {
+	skewed := make(TablePartition, testEntities)
+	for e := range skewed {
+		if e%8 == 7 {
+			skewed[e] = Pid(e % 3)
+		}
+	}
+	for _, cfg := range []Config{
+		{LPs: 3, Partition: RoundRobinPartition{3}},
+		{LPs: 3, Partition: skewed, Policy: GreedyPolicy{Threshold: 0.1}, Rebalance: 50},
+	} {
+		want, final := runEntitiesConfig(t, cfg)
+		if cfg.Policy != nil && CollectStats().Migrations == 0 {
+			t.Fatal("no entity has been migrated")
+		}
+
+		for _, at := range []Time{50, 100, 150} {
+			got, states := runRestarted(t, cfg, 50, at)
+
+			checkTrace(t, cfg.LPs, want, got)
+			for e := range final {
+				if states[e] != final[e] {
+					t.Fatalf("%T, restarted at %d: entity %d has state %+v, want %+v", cfg.Partition, at, e, states[e], final[e])
+				}
+			}
+		}
+	}
+}
+
+/*
+ * the events cancelled before a checkpoint stay cancelled after the
+ * restart: the one after the cut is not executed and cannot be cancelled
+ * again, the retraction of the one before the cut is dropped
+ */
+func TestRestartRetractions(t *testing.T) {
+	const (
+		start = iota + 1
+		cancelled
+		again
+	)
+	var (
+		handles  []EventHandle
+		againErr error
+	)
+	handler := func(ev *Event, l *LocalData) {
+		switch ev.Type.Flag {
+		case start:
+			for _, d := range []Time{20, 100} {
+				h, err := l.Schedule(d, 0, Info{Flag: cancelled})
+				if err == nil {
+					err = l.Cancel(h)
+				}
+				if err != nil {
+					t.Error(err)
+				}
+				handles = append(handles, h)
+			}
+		case cancelled:
+			t.Errorf("the cancelled event %v has been executed", *ev)
+		case again:
+			againErr = l.Cancel(handles[1])
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	SetCheckpointer(nil)
+	Window, GvtThreshold, Lookahead = 0, TOOLARGE, 0
+	SimSetup(1, 200, handler)
+	l := SimInitialize(0)
+	l.NewEvent(CreateEvent(0, 1, Info{Flag: start}))
+	l.NewEvent(CreateEvent(0, 60, Info{Flag: again}))
+	Resume(50)
+	RunLPs(ctx, []*LocalData{l})
+
+	c, err := TakeCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := c.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = ReadCheckpoint(&b); err != nil {
+		t.Fatal(err)
+	}
+	if r := c.LPs[0].Retractions; len(r) != 1 || r[0].M.Ev.Id != handles[1].Id() {
+		t.Fatalf("retractions %+v in the checkpoint, want the event %d", r, handles[1].Id())
+	}
+
+	lps, err := Restart(c, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Resume(EndTime)
+	if RunLPs(ctx, lps).Cancelled {
+		t.Fatal("the simulation did not terminate")
+	}
+	if !errors.Is(againErr, ErrNotPending) {
+		t.Fatalf("the event cancelled before the checkpoint cancelled again: %v", againErr)
+	}
+}
@@ -18,43 +18,53 @@ import (
 )
 
 const MAXBUFFER = 10000
-const MAXALLOCN = 1
 
 var (
-	Chanptr     *[]chan Message
-	lock        chan int = make(chan int)
-	allocations int      = 0
+	Chanptr *[]chan Message
+	lock    chan int = make(chan int)
+
+	/*
+	 * if not nil the messages are handed to sendHook, that must deliver
+	 * them (with deliver) preserving the order between every pair of LPs;
+	 * the tests use it to delay the messages
+	 */
+	sendHook func(msg *Message)
 )
 
+/*
+ * a new set of channels is allocated for every simulation, so that the
+ * messages left by a previous one are discarded
+ */
 func AllocateChans(nChan int) {
-
-	if allocations >= MAXALLOCN {
-		return
-	}
-
 	ch := make([]chan Message, nChan) // this is to make the array
 
 	for i := 0; i < nChan; i++ {
 		ch[i] = make(chan Message, MAXBUFFER) // this is to make the chans
 	}
 	Chanptr = &ch
-	allocations++
 }
 
 /* Send a message to destination */
 func Send(msg *Message) {
+	if sendHook != nil {
+		sendHook(msg)
+		return
+	}
+	deliver(msg)
+}
+
+func deliver(msg *Message) {
 	(*Chanptr)[msg.Receiver] <- *msg
 }
 
+/* non-blocking receive, returns nil if no message is pending */
 func Receive(recvid Pid) *Message {
-	var msg Message
 	var ret *Message
-	var ok bool = false
 
-	msg, ok = <-(*Chanptr)[recvid]
-	if ok {
+	select {
+	case msg := <-(*Chanptr)[recvid]:
 		ret = &msg
-	} else {
+	default:
 		ret = nil
 	}
 
@@ -63,12 +73,22 @@ func Receive(recvid Pid) *Message {
 
 /* blocking receive */
 func BlockingReceive(recvid Pid) *Message {
+	msg, ok := <-(*Chanptr)[recvid]
+	if !ok {
+		fmt.Println("GO-WARP: receive error!")
+		return nil
+	}
+	return &msg
+}
+
+/* blocking receive, returns nil if done is closed before a message arrives */
+func waitMessage(recvid Pid, done <-chan struct{}) *Message {
 	select {
 	case msg := <-(*Chanptr)[recvid]:
 		return &msg
+	case <-done:
+		return nil
 	}
-	fmt.Println("GO-WARP: receive error!")
-	return nil
 }
 
 func Sync() {
@@ -0,0 +1,34 @@
+package warp
+
+import (
+	"testing"
+	"time"
+)
+
+/* Receive returns at once without a pending message, BlockingReceive waits for one */
+func TestReceive(t *testing.T) {
+	ch := []chan Message{make(chan Message, 1)}
+	defer func(old *[]chan Message) { Chanptr = old }(Chanptr)
+	Chanptr = &ch
+
+	if msg := Receive(0); msg != nil {
+		t.Fatalf("received %v without a pending message", *msg)
+	}
+	Send(CreateMessage(1, 0, *CreateEvent(1, 10, Info{})))
+	if msg := Receive(0); msg == nil || msg.Ev.Id != 1 {
+		t.Fatalf("received %v, want the event 1", msg)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		Send(CreateMessage(1, 0, *CreateEvent(2, 20, Info{})))
+	}()
+	if msg := BlockingReceive(0); msg == nil || msg.Ev.Id != 2 {
+		t.Fatalf("received %v, want the event 2", msg)
+	}
+
+	close(ch[0])
+	if msg := BlockingReceive(0); msg != nil {
+		t.Fatalf("received %v from a closed channel", *msg)
+	}
+}
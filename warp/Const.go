@@ -33,7 +33,7 @@ const (
 	/* significant constants */
 	FEWFREEPLACES = 4000 // the free space in an array is too low
 	LISTLEN       = 5000 // the max length of a queue
-	HEAPSIZE      = 500  // heap size
+	HEAPSIZE      = 500  // initial capacity of the heap (different times)
 	TOOLARGE      = 500
 
 	/* possible message colors */
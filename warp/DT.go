@@ -26,6 +26,7 @@ type Info struct { //the simulation modeler can use this field for data transfer
 	From int
 	To   int
 	Flag int32
+	Data []byte // payload of the model, not interpreted by the kernel
 }
 
 type Message struct {
@@ -34,14 +35,27 @@ type Message struct {
 	Ev       Event
 }
 type TimedMessage struct {
-	M Message
-	T Time
+	M   Message
+	T   Time
+	Gen int32 // of the event that logged the message, see rollback
 }
 
+/*
+ * the Id of the events is assigned by the kernel when they are noticed
+ * (NoticeEvent) or inserted as initial events (NewEvent): the LP that
+ * created the event in the high-order bits and its sequence number in
+ * the low-order IDSEQBITS bits. The sequence numbers are not rolled back,
+ * so an event sent again after a rollback has a new Id and only the
+ * anti-message of the cancelled copy can annihilate it
+ */
+const IDSEQBITS = 40
+
 type Event struct {
-	Id   int32 // high-order 16 bits = LP info, low-order 16 bits = event info
-	Time Time
-	Type Info
+	Id     int64 // see IDSEQBITS, > 0 for the events of the model
+	Time   Time
+	Type   Info
+	Sender Pid   // the LP that noticed the event, set by NoticeEvent
+	Gen    int32 // the events with the same time are executed in Gen order, see NoticeEvent
 }
 
 /* interface useful as Elem of a List */
@@ -168,15 +182,19 @@ func Print(L *list.List) {
 	fmt.Println()
 }
 
-/* Checks if an Element with time t is in the list */
+/*
+ * Checks if an Element with time t is in the list, the list is scanned from
+ * the back since t is usually close to the last time (e.g. an anti-message
+ * in the ProcessedEvents)
+ */
 func IsPresent(t Time, L *list.List) bool {
 	ret := false
-	el := L.Front()
+	el := L.Back()
 Loop:
 	for el != nil {
 		elt := el.Value.(Elem).GetTime()
-		if elt < t {
-			el = el.Next()
+		if elt > t {
+			el = el.Prev()
 		} else if elt == t {
 			ret = true
 			break Loop
@@ -202,9 +220,19 @@ Loop:
 	return past.Value.(Elem)
 }
 
-func CreateEvent(id int32, t Time, info Info) *Event {
+/* the LP that created the event with identifier id */
+func IdLP(id int64) Pid {
+	return Pid(id >> IDSEQBITS)
+}
+
+/* the sequence number of the event with identifier id in its LP */
+func IdSeq(id int64) int64 {
+	return id & (1<<IDSEQBITS - 1)
+}
+
+func CreateEvent(id int64, t Time, info Info) *Event {
 	var ev *Event = new(Event)
-	*ev = Event{id, t, info}
+	*ev = Event{Id: id, Time: t, Type: info}
 	return ev
 }
 
@@ -0,0 +1,233 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * ENTITIES
+ *
+ * a model can be written as a set of entities, identified by an integer in
+ * [0, number of entities): the events are addressed to entities (Info.To)
+ * and the kernel routes them to the LP that owns the receiver, as decided
+ * by a Partitioner. Every entity has its own handler and state: before an
+ * event is executed the state of its receiver is saved, so that it can be
+ * restored if the event is rolled back.
+ */
+
+import (
+	list "container/list"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+type Entity interface {
+	Handle(ev *Event, l *LocalData)
+	Save() interface{}     // returns a copy of the state, nil if the entity has no state
+	Restore(s interface{}) // sets the state to a value returned by Save
+}
+
+/*
+ * an Entity that implements Committer is given its events once they can no
+ * longer be rolled back, in time order: when the GVT passes them or, on
+ * the sequential kernel, right after they are executed. It is the place
+ * for the output of the model (e.g. statistics), that must not see the
+ * events that will be undone. Commit is called by the LP that executed
+ * the event and must not change the state saved by Save
+ */
+type Committer interface {
+	Commit(ev *Event)
+}
+
+/*
+ * the events of all the LPs up to the returned time have been given to
+ * their Committer, -1 if none: Commit can use it for the output that
+ * depends on the events of all the entities, e.g. the totals of a period
+ * once the period is over. It grows with the GVT and is MAXTIME once the
+ * simulation is over
+ */
+func CommittedTime() Time {
+	if Sequential {
+		return seqCommitted
+	}
+	t := Time(MAXTIME)
+	for i := range lpCommitted {
+		t = min(t, Time(atomic.LoadInt32(&lpCommitted[i])))
+	}
+	return t
+}
+
+/* an entity without state */
+type EntityFunc func(ev *Event, l *LocalData)
+
+func (f EntityFunc) Handle(ev *Event, l *LocalData) { f(ev, l) }
+func (f EntityFunc) Save() interface{}              { return nil }
+func (f EntityFunc) Restore(s interface{})          {}
+
+/* maps the entities to the LPs, the mapping can change only by migration (see Migrate.go) */
+type Partitioner interface {
+	LP(entity int) Pid
+}
+
+/* contiguous blocks of entities, the first Entities % LPs LPs get one more */
+type BlockPartition struct {
+	Entities, LPs int
+}
+
+/* entity e is owned by LP e % LPs */
+type RoundRobinPartition struct {
+	LPs int
+}
+
+/* the entities are scattered by a hash of their identifier */
+type HashPartition struct {
+	LPs int
+}
+
+/* entity e is owned by LP TablePartition[e] */
+type TablePartition []Pid
+
+/* a saved state of an entity, implements Elem */
+type savedState struct {
+	T      Time
+	Gen    int32
+	Entity int
+	State  interface{}
+}
+
+var (
+	entities  []Entity
+	partition Partitioner
+
+	ckptPartition TablePartition // the partition read by Restart, see SetEntities
+)
+
+func (p BlockPartition) LP(e int) Pid {
+	d, m := p.Entities/p.LPs, p.Entities%p.LPs
+	if e < m*(d+1) {
+		return Pid(e / (d + 1))
+	}
+	return Pid(m + (e-m*(d+1))/d)
+}
+
+func (p RoundRobinPartition) LP(e int) Pid {
+	return Pid(e % p.LPs)
+}
+
+func (p HashPartition) LP(e int) Pid {
+	h := uint32(e) /* murmur3 finalizer */
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return Pid(h % uint32(p.LPs))
+}
+
+func (p TablePartition) LP(e int) Pid {
+	return p[e]
+}
+
+func (s savedState) GetTime() Time {
+	return s.T
+}
+
+func (s savedState) IsEqual(e Elem) bool {
+	s1 := e.(savedState)
+	return s.T == s1.T && s.Entity == s1.Entity
+}
+
+/*
+ * registers the entities of the model, it must be called after SimSetup
+ * (or SeqSetup) with EntityManager as the event handler. If p is nil the
+ * entities are partitioned in blocks. A TablePartition is copied, the
+ * migrations do not change the caller's table. After Restart p is ignored
+ * and the entities get the partition saved in the checkpoint
+ */
+func SetEntities(ents []Entity, p Partitioner) {
+	if ckptPartition != nil {
+		if len(ckptPartition) != len(ents) {
+			fmt.Println("GO-WARP, ERROR: THE CHECKPOINT HAS", len(ckptPartition), "ENTITIES, NOT", len(ents))
+			os.Exit(1)
+		}
+		p, ckptPartition = ckptPartition, nil
+	}
+	if p == nil {
+		p = BlockPartition{len(ents), Lpnum}
+	}
+	if t, ok := p.(TablePartition); ok {
+		p = append(TablePartition(nil), t...)
+	}
+	entities = ents
+	partition = p
+	entityLoad = make([]EntityLoad, len(ents))
+}
+
+/* returns the LP that owns entity e */
+func EntityLP(e int) Pid {
+	return partition.LP(e)
+}
+
+/*
+ * sends an event from the entity that is executing the current event to
+ * entity to, the event can be sent to any entity of any LP. The errors
+ * are the ones of NoticeEvent
+ */
+func NoticeEntity(ev *Event, to int, l *LocalData) error {
+	ev.Type.From = l.entity
+	ev.Type.To = to
+	return NoticeEvent(ev, partition.LP(to), l)
+}
+
+/* the event handler of the models made of entities */
+func EntityManager(ev *Event, l *LocalData) {
+	e := ev.Type.To
+	if e < 0 || e >= len(entities) {
+		fmt.Println(l.IndexLP, "- GO-WARP, ERROR: EVENT FOR THE UNKNOWN ENTITY", e)
+		os.Exit(1)
+	}
+	if partition.LP(e) != l.IndexLP {
+		fmt.Println(l.IndexLP, "- GO-WARP, ERROR: ENTITY", e, "IS NOT OWNED BY THIS LP")
+		os.Exit(1)
+	}
+
+	ent := entities[e]
+	if !Sequential {
+		if s := ent.Save(); s != nil {
+			Insert(savedState{ev.Time, l.gen, e, s}, l.StateLog)
+		}
+	}
+	l.entity = e
+	ent.Handle(ev, l)
+}
+
+/* gives a committed event to its receiver, if it is a Committer */
+func commitEntity(ev *Event) {
+	if e := ev.Type.To; e >= 0 && e < len(entities) {
+		if c, ok := entities[e].(Committer); ok {
+			c.Commit(ev)
+		}
+	}
+}
+
+/* restores the states saved by the events after (t, gen), that are undone, see rollback */
+func restoreStates(t Time, gen int32, states *list.List) {
+	for el := states.Back(); el != nil; el = states.Back() {
+		s := el.Value.(savedState)
+		if !undone(s.T, s.Gen, t, gen) {
+			break
+		}
+		entities[s.Entity].Restore(s.State)
+		states.Remove(el)
+	}
+}
@@ -0,0 +1,228 @@
+package warp
+
+import (
+	"context"
+	"testing"
+)
+
+/*
+ * a stateful entity: the successor of an event depends only on the event,
+ * the state counts the executed events, so that the final states are
+ * wrong if a rolled back event is not undone
+ */
+type testCounter struct {
+	N   int
+	Sum int64
+}
+
+func (c *testCounter) Handle(ev *Event, l *LocalData) {
+	c.N++
+	c.Sum += int64(ev.Type.Flag)
+	h := testHash(ev)
+	next := CreateEvent(0, ev.Time+1+Time(h>>8%10), Info{Flag: ev.Type.Flag + 1})
+	if err := NoticeEntity(next, int(h%testEntities), l); err != nil {
+		panic(err)
+	}
+}
+
+func (c *testCounter) Save() interface{} {
+	return *c
+}
+
+func (c *testCounter) Restore(s interface{}) {
+	*c = s.(testCounter)
+}
+
+func runEntities(t *testing.T, lpn int, p Partitioner, seq bool) ([]TraceRecord, []testCounter) {
+	return runEntitiesConfig(t, Config{LPs: lpn, Partition: p, Sequential: seq})
+}
+
+func runEntitiesConfig(t *testing.T, cfg Config) ([]TraceRecord, []testCounter) {
+	counters := make([]testCounter, testEntities)
+	cfg.EndTime = 200
+	cfg.Entities = testCounters(counters)
+	return traceRun(t, cfg, testEntityInit(24)), counters
+}
+
+func testCounters(counters []testCounter) []Entity {
+	ents := make([]Entity, len(counters))
+	for i := range ents {
+		ents[i] = &counters[i]
+	}
+	return ents
+}
+
+/* the initial events of the entities of every LP */
+func testEntityInit(chains int) func(l *LocalData) error {
+	return func(l *LocalData) error {
+		for _, ev := range testInitial(chains) {
+			if EntityLP(ev.Type.To) == l.IndexLP {
+				l.NewEvent(&ev)
+			}
+		}
+		return nil
+	}
+}
+
+func TestPartitioners(t *testing.T) {
+	parts := []Partitioner{
+		BlockPartition{10, 3},
+		RoundRobinPartition{3},
+		HashPartition{3},
+		TablePartition{2, 2, 1, 0, 0, 1, 2, 0, 1, 1},
+	}
+	for _, p := range parts {
+		var n [3]int
+		for e := 0; e < 10; e++ {
+			lp := p.LP(e)
+			if lp < 0 || lp >= 3 {
+				t.Fatalf("%T: entity %d mapped to LP %d", p, e, lp)
+			}
+			n[lp]++
+		}
+		t.Logf("%T: %v", p, n)
+	}
+
+	/* the blocks are contiguous and balanced */
+	b := BlockPartition{10, 3}
+	want := []Pid{0, 0, 0, 0, 1, 1, 1, 2, 2, 2}
+	for e := range want {
+		if b.LP(e) != want[e] {
+			t.Fatalf("BlockPartition: entity %d mapped to LP %d, want %d", e, b.LP(e), want[e])
+		}
+	}
+}
+
+/* the rolled back events must not change the state of the entities */
+func TestEntityState(t *testing.T) {
+	for i, p := range []Partitioner{BlockPartition{testEntities, 4}, RoundRobinPartition{4}, HashPartition{4}} {
+		seq, final := runEntities(t, 4, p, true)
+
+		d := &testDelayer{links: make(map[[2]Pid]*testLink), seed: int64(i)}
+		sendHook = d.send
+		par, states := runEntities(t, 4, p, false)
+		d.stop()
+
+		checkTrace(t, 4, seq, par)
+		for e := range final {
+			if states[e] != final[e] {
+				t.Fatalf("%T: entity %d has state %+v, want %+v", p, e, states[e], final[e])
+			}
+		}
+		t.Logf("%T: %d rollbacks", p, CollectStats().Rollbacks)
+	}
+}
+
+/* the LP and the sender of the events depend on the placement of the entities */
+func ignoreLP(trace []TraceRecord) {
+	for i := range trace {
+		trace[i].LP = 0
+		trace[i].Ev.Sender = 0
+	}
+}
+
+func TestMigration(t *testing.T) {
+	skewed := make(TablePartition, testEntities)
+	for e := range skewed {
+		if e%8 == 7 {
+			skewed[e] = Pid(e % 3)
+		}
+	}
+	orig := append(TablePartition(nil), skewed...)
+	seq, final := runEntities(t, 3, skewed, true)
+
+	for _, every := range []Time{10, 45} {
+		par, states := runEntitiesConfig(t, Config{LPs: 3, Partition: skewed,
+			Policy: GreedyPolicy{Threshold: 0.1}, Rebalance: every})
+		s := CollectStats()
+		if s.Migrations == 0 {
+			t.Errorf("every %d: no entity has been migrated", every)
+		}
+		for e := range skewed {
+			if skewed[e] != orig[e] {
+				t.Fatalf("every %d: the migrations changed the table of the caller, entity %d on LP %d", every, e, skewed[e])
+			}
+		}
+
+		ignoreLP(seq)
+		ignoreLP(par)
+		checkTrace(t, 3, seq, par)
+		for e := range final {
+			if states[e] != final[e] {
+				t.Fatalf("every %d: entity %d has state %+v, want %+v", every, e, states[e], final[e])
+			}
+		}
+		t.Logf("every %d: %d migrations, committed per LP %d %d %d", every, s.Migrations,
+			s.PerLP[0].Committed, s.PerLP[1].Committed, s.PerLP[2].Committed)
+	}
+}
+
+func TestGreedyPolicy(t *testing.T) {
+	loads := []EntityLoad{{0, 0, 50, 0}, {1, 0, 30, 0}, {2, 0, 20, 0}, {3, 1, 10, 0}}
+	moves := GreedyPolicy{Threshold: 0.1}.Plan(loads, 2)
+	lp := []int{100, 10}
+	for _, m := range moves {
+		lp[loads[m.Entity].LP] -= loads[m.Entity].Committed
+		lp[m.To] += loads[m.Entity].Committed
+	}
+	if len(moves) == 0 || lp[0] > 70 || lp[1] > 70 {
+		t.Fatalf("moves %v, load per LP %v", moves, lp)
+	}
+	if m := (GreedyPolicy{Threshold: 0.1, MaxMoves: 1}).Plan(loads, 2); len(m) != 1 {
+		t.Fatalf("MaxMoves 1: moves %v", m)
+	}
+}
+
+/* an event sent to the old owner of a migrated entity is forwarded */
+func TestForward(t *testing.T) {
+	counters := make([]testCounter, testEntities)
+	stop := startTrace(t)
+	defer SetTrace(nil)
+
+	SimSetup(2, 100, EntityManager)
+	SetEntities(testCounters(counters), nil)
+	data := []*LocalData{SimInitialize(0), SimInitialize(1)}
+
+	Resume(50)
+	RunLPs(context.Background(), data)
+	if EntityLP(5) != 0 {
+		t.Fatalf("entity 5 is owned by LP %d", EntityLP(5))
+	}
+	if err := MigrateEntity(5, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []Migration{{-1, 1}, {testEntities, 0}, {5, 2}, {5, -1}} {
+		if err := MigrateEntity(m.Entity, m.To); err == nil || EntityLP(5) != 1 {
+			t.Fatalf("migration %+v: error %v, entity 5 on LP %d", m, err, EntityLP(5))
+		}
+	}
+
+	/*
+	 * LP 1 still believes that entity 5 is on LP 0: the first event is
+	 * moved by Resume, the second one is forwarded by LP 0
+	 */
+	data[1].SimTime = 50 // the messages are sent at the Horizon
+	for i, t := range []Time{60, 70} {
+		ev := CreateEvent(int64(98+i), t, Info{From: 40, To: 5, Flag: int32(98+i) << 16})
+		ev.Sender = 1
+		sendMessage(CreateMessage(1, 0, *ev), data[1])
+		if i == 0 {
+			Resume(100)
+		}
+	}
+	RunLPs(context.Background(), data)
+
+	trace := stop()
+	found := 0
+	for _, r := range trace {
+		if r.Ev.Type.Flag == 98<<16 || r.Ev.Type.Flag == 99<<16 {
+			if r.LP != 1 {
+				t.Fatalf("event %d has been executed by LP %d", r.Ev.Type.Flag>>16, r.LP)
+			}
+			found++
+		}
+	}
+	if found != 2 {
+		t.Fatalf("%d of the 2 forwarded events have been executed", found)
+	}
+}
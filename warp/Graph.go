@@ -0,0 +1,229 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * GRAPH PARTITIONING
+ *
+ * the model describes the communication between its entities with a
+ * weighted graph: the weight of a vertex is the load of the entity, the
+ * weight of an edge the amount of events exchanged by two entities. The
+ * entities are assigned to the LPs so that the weight of the cut edges (the
+ * events sent between LPs) is small and the load is balanced: the LPs are
+ * grown one at a time from a seed, adding the entity most connected to the
+ * LP, then the assignment is refined moving single entities between LPs
+ * while the cut decreases (as in Fiduccia-Mattheyses, without hill
+ * climbing). The result is a TablePartition.
+ */
+
+import (
+	"container/heap"
+)
+
+const GRAPHPASSES = 16 // maximum number of refinement passes
+
+type Graph struct {
+	weight []int
+	adj    []map[int]int
+}
+
+/* an entry of the priority queue used to grow the LPs */
+type growItem struct {
+	v, conn int
+}
+
+type growQueue []growItem
+
+func (q growQueue) Len() int { return len(q) }
+func (q growQueue) Less(i, j int) bool {
+	if q[i].conn != q[j].conn {
+		return q[i].conn > q[j].conn
+	}
+	return q[i].v < q[j].v
+}
+func (q growQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *growQueue) Push(x interface{}) { *q = append(*q, x.(growItem)) }
+func (q *growQueue) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
+
+/* a graph of n entities, each one with weight 1 and no edges */
+func NewGraph(n int) *Graph {
+	g := &Graph{weight: make([]int, n), adj: make([]map[int]int, n)}
+	for i := range g.weight {
+		g.weight[i] = 1
+		g.adj[i] = make(map[int]int)
+	}
+	return g
+}
+
+func (g *Graph) Len() int {
+	return len(g.weight)
+}
+
+func (g *Graph) SetWeight(e, w int) {
+	g.weight[e] = w
+}
+
+/* adds w to the weight of the (undirected) edge between a and b */
+func (g *Graph) AddEdge(a, b, w int) {
+	if a == b {
+		return
+	}
+	g.adj[a][b] += w
+	g.adj[b][a] += w
+}
+
+/* the weight of the edges between entities owned by different LPs */
+func (g *Graph) Cut(p Partitioner) int {
+	cut := 0
+	for a := range g.adj {
+		for b, w := range g.adj[a] {
+			if a < b && p.LP(a) != p.LP(b) {
+				cut += w
+			}
+		}
+	}
+	return cut
+}
+
+/*
+ * assigns the entities to lps LPs, the load of every LP is at most
+ * (1 + imbalance) times the average (or the weight of its heaviest entity)
+ */
+func (g *Graph) Partition(lps int, imbalance float64) TablePartition {
+	n := len(g.weight)
+	part := make(TablePartition, n)
+	if lps <= 1 || n == 0 {
+		return part
+	}
+
+	total := 0
+	for _, w := range g.weight {
+		total += w
+	}
+	limit := int(float64(total) / float64(lps) * (1 + imbalance))
+
+	load := g.grow(part, lps, total, limit)
+	g.refine(part, load, limit)
+	return part
+}
+
+/* the LPs are grown one at a time, the last one gets the remaining entities */
+func (g *Graph) grow(part TablePartition, lps, total, limit int) []int {
+	n := len(g.weight)
+	load := make([]int, lps)
+	assigned := make([]bool, n)
+	conn := make([]int, n) // connection of the unassigned entities with the growing LP
+	next := 0              // the unassigned entities before next are all assigned
+
+	left := total
+	for k := 0; k < lps-1; k++ {
+		target := left / (lps - k)
+		q := &growQueue{}
+		for i := range conn {
+			conn[i] = 0
+		}
+
+		for load[k] < target {
+			v := -1
+			for q.Len() > 0 {
+				it := heap.Pop(q).(growItem)
+				if !assigned[it.v] && it.conn == conn[it.v] {
+					v = it.v
+					break
+				}
+			}
+			if v < 0 { /* a new seed: the first unassigned entity */
+				for next < n && assigned[next] {
+					next++
+				}
+				if next == n {
+					break
+				}
+				v = next
+			}
+			if load[k] > 0 && (load[k]+g.weight[v]-target > target-load[k] || load[k]+g.weight[v] > limit) {
+				break // without v the LP is closer to the target or within the limit
+			}
+
+			assigned[v] = true
+			part[v] = Pid(k)
+			load[k] += g.weight[v]
+			for u, w := range g.adj[v] {
+				if !assigned[u] {
+					conn[u] += w
+					heap.Push(q, growItem{u, conn[u]})
+				}
+			}
+		}
+		left -= load[k]
+	}
+	for v := range part {
+		if !assigned[v] {
+			part[v] = Pid(lps - 1)
+			load[lps-1] += g.weight[v]
+		}
+	}
+	return load
+}
+
+/*
+ * moves single entities to the LP they are most connected to, if the cut
+ * decreases, or out of the LPs whose load is over the limit
+ */
+func (g *Graph) refine(part TablePartition, load []int, limit int) {
+	conn := make([]int, len(load))
+
+	for pass := 0; pass < GRAPHPASSES; pass++ {
+		moved := false
+		for v := range part {
+			for i := range conn {
+				conn[i] = 0
+			}
+			for u, w := range g.adj[v] {
+				conn[part[u]] += w
+			}
+
+			from := part[v]
+			over := load[from] > limit
+			best, gain := from, 0
+			for k := range conn {
+				to := Pid(k)
+				if to == from || load[k]+g.weight[v] > limit {
+					continue
+				}
+				d := conn[k] - conn[from]
+				switch {
+				case over && best == from, d > gain:
+					best, gain = to, d
+				case d == 0 && gain == 0 && best == from && load[k]+g.weight[v] < load[from]:
+					best = to // on equal cut the move must improve the balance
+				}
+			}
+			if best != from {
+				part[v] = best
+				load[from] -= g.weight[v]
+				load[best] += g.weight[v]
+				moved = true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+}
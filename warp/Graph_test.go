@@ -0,0 +1,80 @@
+package warp
+
+import (
+	"testing"
+)
+
+func gridGraph(n int) *Graph {
+	g := NewGraph(n * n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i+1 < n {
+				g.AddEdge(i*n+j, (i+1)*n+j, 1)
+			}
+			if j+1 < n {
+				g.AddEdge(i*n+j, i*n+j+1, 1)
+			}
+		}
+	}
+	return g
+}
+
+func checkBalance(t *testing.T, g *Graph, p TablePartition, lps int, imbalance float64) {
+	load := make([]int, lps)
+	total := 0
+	for e := range p {
+		load[p[e]] += g.weight[e]
+		total += g.weight[e]
+	}
+	for k := range load {
+		if float64(load[k]) > float64(total)/float64(lps)*(1+imbalance) {
+			t.Fatalf("LP %d has load %d of %d: %v", k, load[k], total, load)
+		}
+	}
+}
+
+func TestGraphGrid(t *testing.T) {
+	g := gridGraph(16)
+	for _, lps := range []int{2, 4, 8} {
+		p := g.Partition(lps, 0.05)
+		checkBalance(t, g, p, lps, 0.05)
+
+		cut, rr := g.Cut(p), g.Cut(RoundRobinPartition{lps})
+		t.Logf("%d LPs: cut %d, round robin %d, blocks %d", lps, cut, rr, g.Cut(BlockPartition{256, lps}))
+		if cut > 16*(lps-1)*3/2 {
+			t.Errorf("%d LPs: cut %d", lps, cut)
+		}
+	}
+}
+
+/* two cliques joined by a light edge are split by the light edge */
+func TestGraphCliques(t *testing.T) {
+	g := NewGraph(20)
+	for a := 0; a < 20; a++ {
+		for b := a + 1; b < 20; b++ {
+			if (a < 10) == (b < 10) {
+				g.AddEdge(b, a, 5) // the order of the entities is mixed
+			}
+		}
+	}
+	g.AddEdge(3, 15, 1)
+
+	p := g.Partition(2, 0)
+	if cut := g.Cut(p); cut != 1 {
+		t.Fatalf("cut %d, partition %v", cut, p)
+	}
+}
+
+func TestGraphWeights(t *testing.T) {
+	g := gridGraph(8)
+	for e := 0; e < 8; e++ {
+		g.SetWeight(e, 9) // a heavy row
+	}
+	p := g.Partition(4, 0.1)
+	checkBalance(t, g, p, 4, 0.1)
+
+	/* the partition can be used as the placement of the entities */
+	seq, _ := runEntities(t, 4, p, true)
+	par, _ := runEntities(t, 4, p, false)
+	checkTrace(t, 4, seq, par)
+}
@@ -13,71 +13,181 @@
 
 package warp
 
+/*
+ * GVT EVALUATION
+ *
+ * an evaluation (round) is started by a single LP and completes when all the
+ * LPs have set their local minimum. All the variables are protected by
+ * gvtlock, gvtFlag is also readable without the lock. Every round has an
+ * identifier, carried by the GVTEVAL messages, so that the local minimums
+ * computed for an old round are discarded.
+ *
+ * The GVT is also used for the termination detection: the local minimum of
+ * an LP includes the messages it has sent that are not acknowledged yet, so
+ * a GVT >= Horizon means that no LP has an event to process before the
+ * Horizon and that no such event is in flight. An LP that runs out of
+ * events asks for a new round; if a round ends with a lower GVT while all
+ * the LPs were idle (or while an LP asked for another one) the LP that has
+ * completed it starts a new round.
+ */
+
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
 	lpNum    int
 	localMin []Time
+	idle     []bool // idle[i] is true if LP i had no events before the Horizon
 	gvt      Time
-	gvtFlag  bool
+	gvtRound int32 // identifier of the running (or of the last) evaluation
+	gvtFlag  atomic.Bool
 	gvtlock  sync.Mutex
+	gvtAgain bool // an idle LP has asked for an evaluation while another was running
+	restart  bool // the last evaluation must be followed by a new one
+	stopped  bool // the simulation has been cancelled, the GVT can no longer change
+
+	gvtStart time.Time     // beginning of the running GVT evaluation
+	gvtTotal time.Duration // time spent in GVT evaluations
+	gvtMax   time.Duration
 )
 
 const MAXTIME = 1<<31 - 1
 const EMPTY = -13
 
 func GvtSetup(lpnum int) {
+	gvtlock.Lock()
+	defer gvtlock.Unlock()
 
 	localMin = make([]Time, lpnum)
+	idle = make([]bool, lpnum)
 	lpNum = lpnum
 	gvt = 0
-	gvtFlag = false
+	gvtRound = 0
+	gvtFlag.Store(false)
+	gvtAgain = false
+	restart = false
+	stopped = false
+	gvtTotal = 0
+	gvtMax = 0
+}
+
+/*
+ * starts a new evaluation and returns its identifier, returns -1 if an
+ * evaluation is already running
+ */
+func StartEvaluation(lpnum int) int32 {
+	gvtlock.Lock()
+	defer gvtlock.Unlock()
+
+	if gvtFlag.Load() || stopped {
+		return -1
+	}
+	for i := 0; i < lpnum; i++ {
+		localMin[i] = EMPTY
+	}
+	lpNum = lpnum
+	gvtAgain = false
+	gvtRound++
+	gvtStart = time.Now()
+	gvtFlag.Store(true)
+
+	return gvtRound
 }
 
-func StartEvaluation(lpnum int) {
-	if !gvtFlag {
-		for i := 0; i < lpnum; i++ {
+/* aborts any running evaluation, the GVT is set to t */
+func gvtResume(t Time) {
+	gvtlock.Lock()
+	for i := 0; i < len(localMin); i++ {
+		localMin[i] = EMPTY
+	}
+	gvt = t
+	gvtFlag.Store(false)
+	gvtAgain = false
+	restart = false
+	stopped = false
+	gvtlock.Unlock()
+}
+
+/*
+ * called by the LPs when the simulation is cancelled: the running
+ * evaluation (if any) is aborted, no other one can start and the last
+ * GVT is returned, the same value to every LP
+ */
+func gvtCancel() Time {
+	gvtlock.Lock()
+	defer gvtlock.Unlock()
+
+	if !stopped {
+		stopped = true
+		for i := 0; i < len(localMin); i++ {
 			localMin[i] = EMPTY
 		}
-		lpNum = lpnum
-		gvtFlag = true
+		gvtFlag.Store(false)
+	}
+	return gvt
+}
+
+/* true if the simulation has been cancelled */
+func Cancelled() bool {
+	gvtlock.Lock()
+	defer gvtlock.Unlock()
+
+	return stopped
+}
+
+/*
+ * called by an idle LP whose state has changed since its last local
+ * minimum: returns false if no evaluation is running, otherwise another
+ * one will follow the running evaluation if it does not reach the Horizon
+ */
+func RequestEvaluation() bool {
+	gvtlock.Lock()
+	defer gvtlock.Unlock()
+
+	if !gvtFlag.Load() {
+		return false
 	}
+	gvtAgain = true
+	return true
 }
 
 /* if true the a GVT calculation is running */
 func CheckEvaluation() bool {
-	return gvtFlag
+	return gvtFlag.Load()
 }
 
-func SetLocalMin(time Time, pid Pid) {
+/*
+ * sets the local minimum of LP pid for the given round, idle is true if
+ * the LP has no events before the Horizon. Returns false if the round is
+ * not running (anymore), done is true if this call has completed it
+ */
+func SetLocalMin(time Time, pid Pid, round int32, lpIdle bool) (ok, done bool) {
+	gvtlock.Lock()
+	defer gvtlock.Unlock()
 
-	if !gvtFlag {
-		return
+	if !gvtFlag.Load() || round != gvtRound || stopped {
+		return false, false
 	}
 
 	localMin[pid] = time
+	idle[pid] = lpIdle
 
 	for i := 0; i < len(localMin); i++ {
 		if localMin[i] == EMPTY {
-			return
+			return true, false
 		}
 	}
 
-	gvtlock.Lock()
 	setGVT()
-	gvtlock.Unlock()
+	return true, true
 }
 
+/* must be called holding gvtlock */
 func setGVT() {
 
-	for i := 0; i < len(localMin); i++ {
-		if localMin[i] == EMPTY {
-			return
-		}
-	}
-
 	tmpMin := Time(MAXTIME)
 	for i := 0; i < len(localMin); i++ {
 		if localMin[i] < tmpMin && localMin[i] != NOTIME {
@@ -86,17 +196,50 @@ func setGVT() {
 	}
 	gvt = tmpMin
 
+	allIdle := true
 	for i := 0; i < len(localMin); i++ {
 		localMin[i] = EMPTY
+		allIdle = allIdle && idle[i]
+	}
+	restart = gvt < Horizon && (allIdle || gvtAgain)
+	gvtAgain = false
+
+	d := time.Since(gvtStart)
+	gvtTotal += d
+	if d > gvtMax {
+		gvtMax = d
 	}
-	gvtFlag = false
+	publishGvt(gvt, d)
+	gvtFlag.Store(false)
 	N_gvt++
 }
 
+/* returns the last GVT value, or ERR if an evaluation is running */
 func GetGvt() Time {
+	gvtlock.Lock()
+	defer gvtlock.Unlock()
 
-	if gvtFlag {
+	if gvtFlag.Load() {
 		return ERR
 	}
 	return gvt
 }
+
+/*
+ * returns the GVT computed by the last evaluation and true if a new
+ * evaluation must be started, it is called by the LP that has completed it
+ */
+func gvtResult() (Time, bool) {
+	gvtlock.Lock()
+	defer gvtlock.Unlock()
+
+	return gvt, restart
+}
+
+/* returns the number of completed evaluations and the time spent in them */
+func gvtStats() (int, time.Duration, time.Duration) {
+	gvtlock.Lock()
+	defer gvtlock.Unlock()
+
+	return N_gvt, gvtTotal, gvtMax
+}
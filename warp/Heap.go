@@ -23,7 +23,7 @@ import (
 	"strconv"
 )
 
-const EVARRSIZE = 2000
+const EVARRSIZE = 16 // initial capacity of the array of the events with the same time
 
 type Node struct {
 	time   Time
@@ -60,7 +60,11 @@ func (heap *EventHeap) IsEmpty() bool {
 	return ret
 }
 
-/* Insert an event in the heap, that remains balanced */
+/*
+ * inserts a copy of the event, the heap (HEAPSIZE different times) and the
+ * arrays of the events with the same time (EVARRSIZE) grow when they are
+ * full, so the event is always inserted and true is returned
+ */
 func (heap *EventHeap) Insert(evptr *Event) bool {
 	var ret bool = true
 	var nodepos, pos, fpos int
@@ -68,31 +72,20 @@ func (heap *EventHeap) Insert(evptr *Event) bool {
 	var nod, father Node
 
 	length := len(*heap)
-	capacity := cap(*heap)
 	pos = length
 
 	nodepos = heap.isPresent((*evptr).Time)
 
 	if nodepos > 0 { // another event with timestamp equal to *evptr is already present
 		evArr = (*heap)[nodepos].events
-
-		if len(*evArr) >= cap(*evArr) {
-			ret = false
-		} else {
-			(*evArr) = (*evArr)[0 : len(*evArr)+1]
-			(*evArr)[len(*evArr)-1] = *evptr // the event is placed in the last position
-		}
-	} else if length >= capacity {
-		ret = false
+		(*evArr) = append(*evArr, *evptr) // the event is placed in the last position
 	} else { // an event with timestamp equal to *evptr is not present
 
 		arr := make([]Event, 1, EVARRSIZE)
 		arr[0] = *evptr
 		nod = Node{evptr.Time, &arr}
 
-		(*heap) = (*heap)[0 : length+1]
-
-		(*heap)[pos] = nod
+		(*heap) = append(*heap, nod)
 
 	Loop:
 		for pos > 1 {
@@ -116,6 +109,24 @@ func (heap *EventHeap) Insert(evptr *Event) bool {
 	return ret
 }
 
+/* returns the number of events in the heap */
+func (heap *EventHeap) Count() int {
+	n := 0
+	for i := 1; i < len(*heap); i++ {
+		n += len(*(*heap)[i].events)
+	}
+	return n
+}
+
+/* returns a copy of all the events, in heap order */
+func (heap *EventHeap) Events() []Event {
+	ret := make([]Event, 0, heap.Count())
+	for i := 1; i < len(*heap); i++ {
+		ret = append(ret, *(*heap)[i].events...)
+	}
+	return ret
+}
+
 /* returns the minimum time in the heap */
 func (heap *EventHeap) GetMinTime() Time {
 	if heap.IsEmpty() {
@@ -131,7 +142,14 @@ func (heap *EventHeap) ExtractHead() *Event {
 		return nil
 	}
 
-	head = (*(*heap)[1].events)[len(*(*heap)[1].events)-1]
+	/* the last one of the lowest Gen, see NoticeEvent */
+	evArr := *(*heap)[1].events
+	head = evArr[len(evArr)-1]
+	for i := len(evArr) - 2; i >= 0; i-- {
+		if evArr[i].Gen < head.Gen {
+			head = evArr[i]
+		}
+	}
 	if !heap.Delete(&head) {
 		fmt.Println("GO-WARP: extracthead")
 		os.Exit(1)
@@ -180,6 +198,9 @@ Loop1:
 		}
 
 	} else { // the element to be eliminated is the only one with that timestamp
+		if (*(*heap)[nodepos].events)[0].Id != evptr.Id {
+			return false
+		}
 
 		(*heap)[nodepos] = (*heap)[len(*heap)-1]
 
@@ -315,7 +336,7 @@ func (heap *EventHeap) isPresent(t Time) int {
  * searches, deletes and returns an event using its identifier
  */
 func (heap *EventHeap) DeleteExternId(ev *Event) Event {
-	var ret Event = Event{ERR, ERR, Info{0, 0, 0}}
+	var ret Event = Event{Id: ERR, Time: ERR}
 
 Loop:
 	for i := 1; i < len(*heap); i++ {
@@ -332,16 +353,13 @@ Loop:
 }
 
 func (heap *EventHeap) GetCopy() EventHeap {
-	var ret EventHeap = InitializeHeap()
-
 	l := len(*heap)
-	ret = ret[0:l]
+	ret := make(EventHeap, l, cap(*heap))
 	for i := 0; i < l; i++ {
 		ret[i] = (*heap)[i]
 		if (*heap)[i].events != nil {
 			lea := len(*(*heap)[i].events)
-			ea := make([]Event, EVARRSIZE)
-			ea = ea[0:lea]
+			ea := make([]Event, lea, cap(*(*heap)[i].events))
 			for j := 0; j < lea; j++ {
 				ea[j] = (*(*heap)[i].events)[j]
 			}
@@ -380,17 +398,16 @@ func (heap *EventHeap) GetString() string {
 }
 
 func (heap *EventHeap) DeleteMatching(f func(ev *Event, a ...interface{}) bool, t ...interface{}) {
-	var matching []*Event = make([]*Event, HEAPSIZE)
-	var index int = 0
+	var matching []*Event = make([]*Event, 0, HEAPSIZE)
 
 	for i := 1; i < len(*heap); i++ {
 		for j := 0; j < len(*(*heap)[i].events); j++ {
 			if f(&(*(*heap)[i].events)[j], t[0], t[1]) {
-				matching[index] = &(*(*heap)[i].events)[j]
-				index++
+				matching = append(matching, &(*(*heap)[i].events)[j])
 			}
 		}
 	}
+	index := len(matching)
 Loop:
 	for i := 0; i < index; i++ {
 		if matching[i] == nil {
@@ -0,0 +1,122 @@
+package warp
+
+import (
+	"container/list"
+	"testing"
+)
+
+/*
+ * more different times than HEAPSIZE and more events with the same time
+ * than EVARRSIZE: all the events are inserted and extracted in time order
+ */
+func TestHeapGrowth(t *testing.T) {
+	const times = 3 * HEAPSIZE
+	h := InitializeHeap()
+	n := 0
+	for i := 0; i < times; i++ {
+		tm := Time(i * 7919 % times) // all the times, out of order
+		k := 1
+		if tm%100 == 0 {
+			k = 3 * EVARRSIZE
+		}
+		for j := 0; j < k; j++ {
+			n++
+			if !h.Insert(CreateEvent(int64(n), tm, Info{})) {
+				t.Fatalf("event %d at time %d not inserted", n, tm)
+			}
+		}
+	}
+	if h.Count() != n {
+		t.Fatalf("%d events, want %d", h.Count(), n)
+	}
+
+	c := h.GetCopy()
+	seen := make(map[int64]bool)
+	last := Time(0)
+	for !h.IsEmpty() {
+		ev := h.ExtractHead()
+		if ev.Time < last || seen[ev.Id] {
+			t.Fatalf("event %d at time %d after time %d", ev.Id, ev.Time, last)
+		}
+		seen[ev.Id], last = true, ev.Time
+	}
+	if len(seen) != n || last != times-1 {
+		t.Fatalf("%d events extracted up to time %d, want %d up to %d", len(seen), last, n, times-1)
+	}
+	if c.Count() != n {
+		t.Fatalf("the copy has %d events, want %d", c.Count(), n)
+	}
+}
+
+/* an event is deleted only if both its time and its Id match */
+func TestHeapDelete(t *testing.T) {
+	h := InitializeHeap()
+	h.Insert(CreateEvent(1, 10, Info{}))
+	h.Insert(CreateEvent(2, 20, Info{}))
+	h.Insert(CreateEvent(3, 20, Info{}))
+	for _, ev := range []*Event{CreateEvent(2, 10, Info{}), CreateEvent(4, 20, Info{}), CreateEvent(1, 30, Info{})} {
+		if h.Delete(ev) {
+			t.Fatalf("event %d at time %d deleted", ev.Id, ev.Time)
+		}
+	}
+	if h.Count() != 3 {
+		t.Fatalf("%d events left, want 3", h.Count())
+	}
+	for _, ev := range []*Event{CreateEvent(1, 10, Info{}), CreateEvent(3, 20, Info{}), CreateEvent(2, 20, Info{})} {
+		if !h.Delete(ev) {
+			t.Fatalf("event %d at time %d not deleted", ev.Id, ev.Time)
+		}
+	}
+	if !h.IsEmpty() {
+		t.Fatalf("%d events left", h.Count())
+	}
+}
+
+/* the heap and the list extract the same events in the same order */
+func TestListQueue(t *testing.T) {
+	h, _ := NewQueue(QUEUEHEAP)
+	l, _ := NewQueue(QUEUELIST)
+	for i := 1; i <= 500; i++ {
+		ev := CreateEvent(int64(i), Time(i*7919%97), Info{})
+		ev.Gen = int32(i % 3)
+		h.Insert(ev)
+		l.Insert(ev)
+		if i%5 == 0 {
+			del := CreateEvent(int64(i/2), Time(i/2*7919%97), Info{})
+			if h.Delete(del) != l.Delete(del) {
+				t.Fatalf("event %d deleted by one queue only", del.Id)
+			}
+		}
+		if i%7 == 0 {
+			if a, b := h.ExtractHead(), l.ExtractHead(); a.Id != b.Id {
+				t.Fatalf("heap head %d, list head %d", a.Id, b.Id)
+			}
+		}
+	}
+	if h.Count() != l.Count() {
+		t.Fatalf("%d events in the heap, %d in the list", h.Count(), l.Count())
+	}
+	for !h.IsEmpty() {
+		if h.GetMinTime() != l.GetMinTime() {
+			t.Fatalf("minimum time %d in the heap, %d in the list", h.GetMinTime(), l.GetMinTime())
+		}
+		if a, b := h.ExtractHead(), l.ExtractHead(); a.Id != b.Id {
+			t.Fatalf("heap head %d, list head %d", a.Id, b.Id)
+		}
+	}
+	if !l.IsEmpty() || l.ExtractHead() != nil || l.GetMinTime() != NOTIME {
+		t.Fatalf("%d events left in the list", l.Count())
+	}
+}
+
+func TestIsPresent(t *testing.T) {
+	L := list.New()
+	for _, tm := range []Time{1, 3, 3, 5} {
+		Insert(TimedMessage{T: tm}, L)
+	}
+	for tm, want := range map[Time]bool{0: false, 1: true, 2: false, 3: true, 4: false, 5: true, 6: false} {
+		if IsPresent(tm, L) != want {
+			t.Errorf("IsPresent(%d) = %v", tm, !want)
+		}
+	}
+}
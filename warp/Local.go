@@ -21,6 +21,7 @@ package warp
 
 import (
 	list "container/list"
+	"context"
 	"fmt"
 	"os"
 )
@@ -30,14 +31,28 @@ type LocalData struct {
 	SimTime            Time
 	Gvt                Time
 	IndexLP            Pid
-	FutureEvents       EventHeap
+	FutureEvents       EventQueue // see Queue.go
 	ProcessedEvents    *list.List
 	MsgSent            *list.List
 	AntiMsg2Annihilate *list.List
 	OutgoingMsg        *list.List
 	Acked              *list.List
+	StateLog           *list.List // saved states of the entities, see Entity.go
+	DrawLog            *list.List // draws of the random streams, see Random.go
+	Retractions        *list.List // the events cancelled by the LP, see Schedule.go
 	Pending            bool
 	GvtFlag            bool
+	Stats              LPStats
+
+	metricsTick int
+	changed     bool            // the state has changed since the last local minimum
+	executing   bool            // the EventManager is running, it can still send events at SimTime
+	done        <-chan struct{} // closed when the simulation is cancelled
+	entity      int             // the entity that is executing the current event
+	drawState   []uint64        // the state of the random stream of the current event, see Random.go
+	drawCheck   []uint64
+	lastId      int64 // sequence number of the last event created by the LP
+	gen         int32 // the Gen of the current event
 }
 
 /*
@@ -54,20 +69,74 @@ func Initialize(i Pid) *LocalData {
 	d.Gvt = 0
 	d.Pending = true
 	d.GvtFlag = false
-	d.FutureEvents = InitializeHeap()
+	d.changed = true
+	d.FutureEvents = newQueue()
 	d.ProcessedEvents = NewList()
 	d.MsgSent = NewList()
 	d.AntiMsg2Annihilate = NewList()
 	d.OutgoingMsg = NewList()
 	d.Acked = NewList()
+	d.StateLog = NewList()
+	d.DrawLog = NewList()
+	d.Retractions = NewList()
+	initLPStats(&d.Stats, i)
 
 	return &d
 }
 
+/* a queue of the Queue implementation, that Run has validated */
+func newQueue() EventQueue {
+	q, err := NewQueue(Queue)
+	if err != nil {
+		fmt.Println("GO-WARP, ERROR:", err)
+		os.Exit(1)
+	}
+	return q
+}
+
+/* inserts an initial event of the LP, its Id is assigned by the kernel */
 func (l *LocalData) NewEvent(ev *Event) {
+	ev.Id = l.newId()
+	l.insertEvent(ev)
+}
+
+var (
+	failCtx    context.Context // done when a LP has failed, see Fail
+	failCancel context.CancelCauseFunc
+)
+
+/*
+ * stops the simulation because of an error of the model, e.g. an event
+ * refused by NoticeEvent or of an unknown kind: all the LPs stop as when
+ * the simulation is cancelled and Run returns the error. Only the first
+ * error is kept. The event handler should return after calling Fail
+ */
+func (l *LocalData) Fail(err error) {
+	failCancel(fmt.Errorf("GO-WARP: LP %d at time %d: %w", l.IndexLP, l.SimTime, err))
+}
+
+/* the error of the first LP that has failed in the current simulation, or nil */
+func Failure() error {
+	if failCtx == nil || failCtx.Err() == nil {
+		return nil
+	}
+	return context.Cause(failCtx)
+}
+
+/* inserts an event that already has an Id, as a migrated or a restored one */
+func (l *LocalData) insertEvent(ev *Event) {
 	if !l.FutureEvents.Insert(ev) {
 		fmt.Println("GO-WARP, ERROR: EVENT NOT INSERTED")
 		l.FutureEvents.Print()
 		os.Exit(1)
 	}
 }
+
+func (l *LocalData) newId() int64 {
+	l.lastId++
+	if l.lastId >= 1<<IDSEQBITS {
+		fmt.Println(l.IndexLP, "- GO-WARP, ERROR: TOO MANY EVENTS FOR THE EVENT IDENTIFIERS")
+		os.Exit(1)
+	}
+	return int64(l.IndexLP)<<IDSEQBITS | l.lastId
+}
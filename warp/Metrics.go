@@ -0,0 +1,287 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * LIVE METRICS
+ *
+ * when enabled by ServeMetrics, every LP periodically publishes a snapshot
+ * of its counters and queue lengths using atomic variables, that are
+ * exported over HTTP in the Prometheus text format (/metrics) and with
+ * expvar (/debug/vars) while the simulation is running. The rates are
+ * computed by a RateSampler from the counters, that only grow during a
+ * run, and from the sample taken at the previous call of the same
+ * sampler: every endpoint has its own, so the scrapes of one do not
+ * change the rates of the other.
+ */
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const METRICSTICK = 256 // the LP publishes its metrics every METRICSTICK iterations
+
+type liveLP struct {
+	simTime    atomic.Int64
+	processed  atomic.Int64
+	committed  atomic.Int64
+	rollbacks  atomic.Int64
+	rolledBack atomic.Int64
+	future     atomic.Int64 // events in FutureEvents
+	done       atomic.Int64 // events in ProcessedEvents
+	msgSent    atomic.Int64
+	outgoing   atomic.Int64
+}
+
+/* the counters of a simulation, replaced by metricsSetup */
+type liveRun struct {
+	lps   []liveLP
+	start time.Time
+}
+
+type lpRate struct {
+	processed, rollbacks int64
+}
+
+var (
+	metricsOn    atomic.Bool
+	liveRuns     atomic.Pointer[liveRun]
+	liveGvt      atomic.Int64
+	liveGvtRound atomic.Int64 // latency of the last GVT round (ns)
+	liveGvtN     atomic.Int64
+
+	expvarOnce    sync.Once
+	expvarSampler RateSampler // of /debug/vars
+)
+
+/* computes the rates since its previous sample, it can be used by several goroutines */
+type RateSampler struct {
+	mu    sync.Mutex
+	run   *liveRun // the simulation of the previous sample
+	last  time.Time
+	count []lpRate
+}
+
+/* a snapshot of the live metrics, as published with expvar */
+type LiveLP struct {
+	LP              Pid     `json:"lp"`
+	SimTime         Time    `json:"sim_time"`
+	Processed       int64   `json:"processed"`
+	Committed       int64   `json:"committed"`
+	Rollbacks       int64   `json:"rollbacks"`
+	RolledBack      int64   `json:"rolled_back"`
+	EventRate       float64 `json:"event_rate"`    // processed events per second since the previous sample, see RateSampler
+	RollbackRate    float64 `json:"rollback_rate"` // rollbacks per second since the previous sample
+	FutureEvents    int64   `json:"future_events"`
+	ProcessedEvents int64   `json:"processed_events"`
+	MsgSent         int64   `json:"msg_sent"`
+	OutgoingMsg     int64   `json:"outgoing_msg"`
+}
+
+type LiveMetrics struct {
+	Gvt        Time          `json:"gvt"`
+	GvtRounds  int64         `json:"gvt_rounds"`
+	GvtLatency time.Duration `json:"gvt_round_ns"`
+	Elapsed    time.Duration `json:"elapsed_ns"`
+	LPs        []LiveLP      `json:"lps"`
+}
+
+func metricsSetup(lpn int) {
+	liveGvt.Store(0)
+	liveGvtRound.Store(0)
+	liveGvtN.Store(0)
+
+	liveRuns.Store(&liveRun{lps: make([]liveLP, lpn), start: time.Now()})
+}
+
+/*
+ * starts an HTTP server exposing the live metrics on addr (e.g. ":9090"),
+ * it must be called before SimSetup. The Addr of the returned server is
+ * the address actually used, Close or Shutdown stop it.
+ */
+func ServeMetrics(addr string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	expvarOnce.Do(func() {
+		expvar.Publish("gowarp", expvar.Func(func() interface{} { return expvarSampler.Sample() }))
+	})
+
+	var prom RateSampler
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WritePrometheus(w, prom.Sample())
+	})
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	srv := &http.Server{Addr: ln.Addr().String(), Handler: mux}
+	metricsOn.Store(true)
+	go func() {
+		if err := srv.Serve(ln); err != http.ErrServerClosed {
+			fmt.Println("GO-WARP, ERROR: THE METRICS SERVER HAS STOPPED:", err)
+		}
+	}()
+
+	return srv, nil
+}
+
+/* called by the LP in its main loop */
+func publishMetrics(data *LocalData, force bool) {
+	if !metricsOn.Load() {
+		return
+	}
+	data.metricsTick++
+	if !force && data.metricsTick%METRICSTICK != 0 {
+		return
+	}
+
+	m := &liveRuns.Load().lps[data.IndexLP]
+	m.simTime.Store(int64(data.SimTime))
+	m.processed.Store(int64(data.Stats.Processed))
+	m.committed.Store(int64(data.Stats.Committed))
+	m.rollbacks.Store(int64(data.Stats.Rollbacks))
+	m.rolledBack.Store(int64(data.Stats.RolledBack))
+	m.future.Store(int64(data.FutureEvents.Count()))
+	m.done.Store(int64(data.ProcessedEvents.Len()))
+	m.msgSent.Store(int64(data.MsgSent.Len()))
+	m.outgoing.Store(int64(data.OutgoingMsg.Len()))
+}
+
+/* called when a GVT round is completed */
+func publishGvt(t Time, round time.Duration) {
+	liveGvt.Store(int64(t))
+	liveGvtRound.Store(int64(round))
+	liveGvtN.Add(1)
+}
+
+/* a snapshot of the live metrics, without the rates: see RateSampler */
+func GetLiveMetrics() *LiveMetrics {
+	lm, _ := snapshot()
+	return lm
+}
+
+func snapshot() (*LiveMetrics, *liveRun) {
+	now := time.Now()
+	run := liveRuns.Load()
+	var lps []liveLP
+	if run != nil {
+		lps = run.lps
+	}
+
+	lm := &LiveMetrics{
+		Gvt:        Time(liveGvt.Load()),
+		GvtRounds:  liveGvtN.Load(),
+		GvtLatency: time.Duration(liveGvtRound.Load()),
+		Elapsed:    now.Sub(StartTime),
+		LPs:        make([]LiveLP, len(lps)),
+	}
+	for i := range lps {
+		m := &lps[i]
+		l := &lm.LPs[i]
+
+		l.LP = Pid(i)
+		l.SimTime = Time(m.simTime.Load())
+		l.Processed = m.processed.Load()
+		l.Committed = m.committed.Load()
+		l.Rollbacks = m.rollbacks.Load()
+		l.RolledBack = m.rolledBack.Load()
+		l.FutureEvents = m.future.Load()
+		l.ProcessedEvents = m.done.Load()
+		l.MsgSent = m.msgSent.Load()
+		l.OutgoingMsg = m.outgoing.Load()
+	}
+	return lm, run
+}
+
+/*
+ * a snapshot of the live metrics with the rates since the previous sample,
+ * or since the start of the simulation for the first sample of a run
+ */
+func (s *RateSampler) Sample() *LiveMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lm, run := snapshot()
+	now := time.Now()
+	if run != s.run {
+		s.run, s.count = run, make([]lpRate, len(lm.LPs))
+		if run != nil {
+			s.last = run.start
+		}
+	}
+	dt := now.Sub(s.last).Seconds()
+	s.last = now
+
+	for i := range lm.LPs {
+		l := &lm.LPs[i]
+		if dt > 0 {
+			l.EventRate = float64(l.Processed-s.count[i].processed) / dt
+			l.RollbackRate = float64(l.Rollbacks-s.count[i].rollbacks) / dt
+		}
+		s.count[i] = lpRate{l.Processed, l.Rollbacks}
+	}
+	return lm
+}
+
+/* writes the live metrics in the Prometheus text exposition format */
+func WritePrometheus(w io.Writer, lm *LiveMetrics) {
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP gowarp_%s %s\n# TYPE gowarp_%s %s\n", name, help, name, typ)
+	}
+
+	metric("gvt", "gauge", "Current global virtual time.")
+	fmt.Fprintf(w, "gowarp_gvt %d\n", lm.Gvt)
+	metric("gvt_rounds_total", "counter", "Completed GVT evaluations.")
+	fmt.Fprintf(w, "gowarp_gvt_rounds_total %d\n", lm.GvtRounds)
+	metric("gvt_round_seconds", "gauge", "Latency of the last GVT evaluation.")
+	fmt.Fprintf(w, "gowarp_gvt_round_seconds %g\n", lm.GvtLatency.Seconds())
+
+	perLP := []struct {
+		name, typ, help string
+		value           func(l *LiveLP) interface{}
+	}{
+		{"lp_sim_time", "gauge", "Local simulation time of the LP.", func(l *LiveLP) interface{} { return l.SimTime }},
+		{"lp_processed_total", "counter", "Events executed by the LP, including the rolled back ones.", func(l *LiveLP) interface{} { return l.Processed }},
+		{"lp_committed_total", "counter", "Events committed by the LP.", func(l *LiveLP) interface{} { return l.Committed }},
+		{"lp_rollbacks_total", "counter", "Rollbacks of the LP.", func(l *LiveLP) interface{} { return l.Rollbacks }},
+		{"lp_rolled_back_total", "counter", "Events undone by the rollbacks of the LP.", func(l *LiveLP) interface{} { return l.RolledBack }},
+		{"lp_event_rate", "gauge", "Events executed per second since the previous scrape.", func(l *LiveLP) interface{} { return l.EventRate }},
+		{"lp_rollback_rate", "gauge", "Rollbacks per second since the previous scrape.", func(l *LiveLP) interface{} { return l.RollbackRate }},
+	}
+	for _, m := range perLP {
+		metric(m.name, m.typ, m.help)
+		for i := range lm.LPs {
+			fmt.Fprintf(w, "gowarp_%s{lp=\"%d\"} %v\n", m.name, i, m.value(&lm.LPs[i]))
+		}
+	}
+
+	metric("lp_queue_length", "gauge", "Length of the LP queues.")
+	for i := range lm.LPs {
+		l := &lm.LPs[i]
+		fmt.Fprintf(w, "gowarp_lp_queue_length{lp=\"%d\",queue=\"FutureEvents\"} %d\n", i, l.FutureEvents)
+		fmt.Fprintf(w, "gowarp_lp_queue_length{lp=\"%d\",queue=\"ProcessedEvents\"} %d\n", i, l.ProcessedEvents)
+		fmt.Fprintf(w, "gowarp_lp_queue_length{lp=\"%d\",queue=\"MsgSent\"} %d\n", i, l.MsgSent)
+		fmt.Fprintf(w, "gowarp_lp_queue_length{lp=\"%d\",queue=\"OutgoingMsg\"} %d\n", i, l.OutgoingMsg)
+	}
+}
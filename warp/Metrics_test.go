@@ -0,0 +1,177 @@
+package warp
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/* the samples of /metrics, by name with labels */
+func scrapeMetrics(t *testing.T, addr string) map[string]float64 {
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	samples := make(map[string]float64)
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("/metrics: %q: %v", line, err)
+		}
+		samples[line[:i]] = v
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
+
+func scrapeVars(t *testing.T, addr string) *LiveMetrics {
+	resp, err := http.Get("http://" + addr + "/debug/vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var vars struct {
+		Gowarp *LiveMetrics `json:"gowarp"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.Gowarp == nil {
+		t.Fatal("/debug/vars: no gowarp variable")
+	}
+	return vars.Gowarp
+}
+
+/* LP 0 stops at time 100 until the metrics have been scraped, the other LPs go on */
+func TestMetrics(t *testing.T) {
+	srv, err := ServeMetrics("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	addr := srv.Addr
+
+	var once sync.Once
+	reached, release := make(chan bool), make(chan bool)
+	handler := func(ev *Event, l *LocalData) {
+		if l.IndexLP == 0 && ev.Time >= 100 {
+			once.Do(func() {
+				close(reached)
+				<-release
+			})
+		}
+		testModel(ev, l)
+	}
+
+	const lpn = 4
+	var res *Result
+	done := make(chan bool)
+	go func() {
+		res, err = Run(Config{LPs: lpn, EndTime: 300, Handler: handler}, testInit(32))
+		close(done)
+	}()
+	select {
+	case <-reached:
+	case <-time.After(30 * time.Second):
+		t.Fatal("LP 0 did not reach time 100")
+	}
+
+	/* the other LPs publish their counters while LP 0 is stopped */
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		m := scrapeMetrics(t, addr)
+		processed := 0.0
+		for i := 1; i < lpn; i++ {
+			processed += m["gowarp_lp_processed_total{lp=\""+strconv.Itoa(i)+"\"}"]
+		}
+		if _, ok := m["gowarp_gvt"]; !ok {
+			t.Fatal("/metrics: no gowarp_gvt")
+		}
+		if processed > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("/metrics: no processed events during the run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if lm := scrapeVars(t, addr); len(lm.LPs) != lpn || lm.Gvt >= 300 {
+		t.Fatalf("/debug/vars during the run: %+v", lm)
+	}
+	close(release)
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("the simulation did not terminate")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	/* the final counters are the statistics of the run */
+	m := scrapeMetrics(t, addr)
+	lm := scrapeVars(t, addr)
+	for i, s := range res.Stats.PerLP {
+		lp := "{lp=\"" + strconv.Itoa(i) + "\"}"
+		l := &lm.LPs[i]
+		if m["gowarp_lp_processed_total"+lp] != float64(s.Processed) || m["gowarp_lp_committed_total"+lp] != float64(s.Committed) ||
+			m["gowarp_lp_rollbacks_total"+lp] != float64(s.Rollbacks) || m["gowarp_lp_rolled_back_total"+lp] != float64(s.RolledBack) {
+			t.Fatalf("/metrics: LP %d has different counters than %+v", i, s)
+		}
+		if l.Processed != int64(s.Processed) || l.Committed != int64(s.Committed) ||
+			l.Rollbacks != int64(s.Rollbacks) || l.RolledBack != int64(s.RolledBack) {
+			t.Fatalf("/debug/vars: LP %d has %+v, want %+v", i, *l, s)
+		}
+	}
+	if m["gowarp_gvt_rounds_total"] != float64(lm.GvtRounds) || lm.GvtRounds == 0 {
+		t.Fatalf("%v GVT rounds in /metrics, %d in /debug/vars", m["gowarp_gvt_rounds_total"], lm.GvtRounds)
+	}
+
+	if err := srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := http.Get("http://" + addr + "/metrics"); err == nil {
+		t.Fatal("the metrics server is still running after Close")
+	}
+}
+
+/* every sampler has its own previous sample, the samples of one do not change the rates of another */
+func TestRateSampler(t *testing.T) {
+	metricsSetup(1)
+	liveRuns.Load().lps[0].processed.Store(100)
+	time.Sleep(10 * time.Millisecond)
+
+	var a, b RateSampler
+	if r := a.Sample().LPs[0].EventRate; r <= 0 {
+		t.Fatalf("first sample: rate %v since the start of the run", r)
+	}
+	if r := b.Sample().LPs[0].EventRate; r <= 0 {
+		t.Fatalf("first sample of another sampler: rate %v since the start of the run", r)
+	}
+	if r := a.Sample().LPs[0].EventRate; r != 0 {
+		t.Fatalf("no new event since the previous sample, rate %v", r)
+	}
+
+	liveRuns.Load().lps[0].processed.Store(150)
+	time.Sleep(10 * time.Millisecond)
+	if r := b.Sample().LPs[0].EventRate; r <= 0 || r > 50/0.01 {
+		t.Fatalf("50 new events in more than 10ms, rate %v", r)
+	}
+}
@@ -0,0 +1,251 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * ENTITY MIGRATION
+ *
+ * the simulation is executed in slices of simulated time, as done for the
+ * checkpoints: at the end of every slice the GVT is equal to the Horizon,
+ * all the processed events are committed and no message is in flight. At
+ * that point a MigrationPolicy looks at the load of the entities in the
+ * last slice and decides which ones move to another LP: their pending
+ * events are moved with them and the routing table is updated. The state
+ * of an entity is kept by the Entity itself, it has no saved copies at
+ * the Horizon. An event that reaches an LP that does not own its receiver
+ * anymore is forwarded to the new owner.
+ *
+ * the migrations are done at the Horizon, not at the end of every GVT
+ * round: a GVT round does not stop the LPs, so at that point the entity
+ * could still have events in flight, or events above the GVT that a
+ * rollback would have to find on the old owner together with its saved
+ * states. The end of a slice is the one GVT boundary at which none of
+ * this can happen, and Config.Rebalance sets how often it comes.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+/* the load of an entity since the previous rebalancing */
+type EntityLoad struct {
+	Entity     int
+	LP         Pid // the current owner
+	Committed  int // committed events
+	RolledBack int // executed events that have been undone
+}
+
+type Migration struct {
+	Entity int
+	To     Pid
+}
+
+/* decides the migrations, given the load of all the entities */
+type MigrationPolicy interface {
+	Plan(loads []EntityLoad, lps int) []Migration
+}
+
+/*
+ * moves entities from the most to the least loaded LP (the load is the
+ * number of committed events) until the most loaded LP is within
+ * Threshold (e.g. 0.1 = 10%) of the average, at most MaxMoves per
+ * rebalancing (0 means no limit)
+ */
+type GreedyPolicy struct {
+	Threshold float64
+	MaxMoves  int
+}
+
+var (
+	entityLoad  []EntityLoad // entityLoad[e] is updated by the owner of entity e only
+	nMigrations int
+)
+
+/* the rollback rate of the entity: undone events / executed events */
+func (l *EntityLoad) RollbackRate() float64 {
+	if l.Committed+l.RolledBack == 0 {
+		return 0
+	}
+	return float64(l.RolledBack) / float64(l.Committed+l.RolledBack)
+}
+
+func (p GreedyPolicy) Plan(loads []EntityLoad, lps int) []Migration {
+	var moves []Migration
+
+	lpLoad := make([]int, lps)
+	owner := make([]Pid, len(loads))
+	total := 0
+	for i := range loads {
+		lpLoad[loads[i].LP] += loads[i].Committed
+		owner[i] = loads[i].LP
+		total += loads[i].Committed
+	}
+	avg := float64(total) / float64(lps)
+
+	/* the heaviest entities are considered first */
+	order := make([]int, len(loads))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return loads[order[i]].Committed > loads[order[j]].Committed
+	})
+
+	for p.MaxMoves == 0 || len(moves) < p.MaxMoves {
+		max, min := 0, 0
+		for i := range lpLoad {
+			if lpLoad[i] > lpLoad[max] {
+				max = i
+			}
+			if lpLoad[i] < lpLoad[min] {
+				min = i
+			}
+		}
+		if float64(lpLoad[max]) <= avg*(1+p.Threshold) {
+			break
+		}
+
+		/* the heaviest entity that does not overload the destination */
+		gap := lpLoad[max] - lpLoad[min]
+		moved := false
+		for _, e := range order {
+			w := loads[e].Committed
+			if owner[e] != Pid(max) || w == 0 || w >= gap {
+				continue
+			}
+			owner[e] = Pid(min)
+			lpLoad[max] -= w
+			lpLoad[min] += w
+			moves = append(moves, Migration{loads[e].Entity, Pid(min)})
+			moved = true
+			break
+		}
+		if !moved {
+			break
+		}
+	}
+	return moves
+}
+
+/* returns the load of all the entities since the previous rebalancing */
+func EntityLoads() []EntityLoad {
+	ret := make([]EntityLoad, len(entityLoad))
+	for e := range entityLoad {
+		ret[e] = entityLoad[e]
+		ret[e].Entity = e
+		ret[e].LP = partition.LP(e)
+	}
+	return ret
+}
+
+/*
+ * applies the policy to a simulation stopped at the Horizon and resets the
+ * load counters, returns the migrations done. On an invalid migration the
+ * previous ones are kept and the error is returned. Resume continues the
+ * run
+ */
+func Rebalance(p MigrationPolicy) ([]Migration, error) {
+	quiesce()
+
+	moves := p.Plan(EntityLoads(), Lpnum)
+	for i, m := range moves {
+		if err := MigrateEntity(m.Entity, m.To); err != nil {
+			return moves[:i], err
+		}
+	}
+	for e := range entityLoad {
+		entityLoad[e] = EntityLoad{}
+	}
+	return moves, nil
+}
+
+/*
+ * moves entity e, with its pending events, to LP to. The simulation must
+ * be stopped at the Horizon (see Rebalance)
+ */
+func MigrateEntity(e int, to Pid) error {
+	if entities == nil {
+		return errors.New("GO-WARP: no entities to migrate")
+	}
+	if e < 0 || e >= len(entities) {
+		return fmt.Errorf("GO-WARP: cannot migrate the unknown entity %d", e)
+	}
+	if to < 0 || int(to) >= Lpnum || lpData[to] == nil {
+		return fmt.Errorf("GO-WARP: cannot migrate entity %d to the unknown LP %d", e, to)
+	}
+	from := partition.LP(e)
+	if from == to {
+		return nil
+	}
+	if lpData[from] == nil {
+		return fmt.Errorf("GO-WARP: entity %d is owned by the unknown LP %d", e, from)
+	}
+
+	table, ok := partition.(TablePartition)
+	if !ok {
+		table = make(TablePartition, len(entities))
+		for i := range table {
+			table[i] = partition.LP(i)
+		}
+		partition = table
+	}
+	table[e] = to
+
+	src, dst := lpData[from], lpData[to]
+	evs := src.FutureEvents.Events()
+	src.FutureEvents = newQueue()
+	for i := range evs {
+		if evs[i].Type.To == e {
+			dst.insertEvent(&evs[i])
+		} else {
+			src.insertEvent(&evs[i])
+		}
+	}
+	nMigrations++
+	return nil
+}
+
+func loadCommitted(ev *Event) {
+	if e := ev.Type.To; e >= 0 && e < len(entityLoad) {
+		entityLoad[e].Committed++
+	}
+}
+
+func loadRolledBack(ev *Event) {
+	if e := ev.Type.To; e >= 0 && e < len(entityLoad) {
+		entityLoad[e].RolledBack++
+	}
+}
+
+/*
+ * if the receiver of the event (or anti-message) is not owned by this LP
+ * the message is sent to the new owner and true is returned. The forwarded
+ * message counts for the GVT with the time of the event
+ */
+func forwardEntity(msg *Message, data *LocalData) bool {
+	if entities == nil || msg.Ev.Type.To < 0 || msg.Ev.Type.To >= len(entities) {
+		return false
+	}
+	owner := partition.LP(msg.Ev.Type.To)
+	if owner == data.IndexLP {
+		return false
+	}
+
+	fwd := CreateMessage(data.IndexLP, owner, msg.Ev)
+	Insert(TimedMessage{*fwd, msg.Ev.Time, data.gen}, data.OutgoingMsg)
+	Send(fwd)
+	return true
+}
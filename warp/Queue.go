@@ -0,0 +1,128 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * PENDING EVENT SET
+ *
+ * the future events of a LP are kept in an EventQueue, whose
+ * implementation is chosen by Queue before SimSetup: the heap of Heap.go
+ * (QUEUEHEAP, the default) or a sorted list (QUEUELIST), that is faster
+ * when every LP has a few pending events. Both extract the events with the
+ * same time in the same order, so the choice does not change the results.
+ */
+
+import (
+	"fmt"
+	"sort"
+)
+
+/* queue implementations */
+const (
+	QUEUEHEAP = iota // EventHeap
+	QUEUELIST = iota // ListQueue
+)
+
+type EventQueue interface {
+	Insert(ev *Event) bool // inserts a copy of the event
+	Delete(ev *Event) bool // deletes the event with the time and the Id of ev
+	ExtractHead() *Event   // nil if the queue is empty
+	GetMinTime() Time      // NOTIME if the queue is empty
+	IsEmpty() bool
+	Count() int
+	Events() []Event // a copy of all the events
+	Print()
+}
+
+var Queue = QUEUEHEAP // the implementation of the future events of the LPs
+
+func NewQueue(kind int) (EventQueue, error) {
+	switch kind {
+	case QUEUEHEAP:
+		h := InitializeHeap()
+		return &h, nil
+	case QUEUELIST:
+		return new(ListQueue), nil
+	}
+	return nil, fmt.Errorf("GO-WARP: unknown queue implementation %d", kind)
+}
+
+/* the events sorted by time, the ones with the same time in insertion order */
+type ListQueue struct {
+	evs []Event
+}
+
+func (q *ListQueue) Insert(ev *Event) bool {
+	i := sort.Search(len(q.evs), func(i int) bool { return q.evs[i].Time > ev.Time })
+	q.evs = append(q.evs, Event{})
+	copy(q.evs[i+1:], q.evs[i:])
+	q.evs[i] = *ev
+	return true
+}
+
+func (q *ListQueue) Delete(ev *Event) bool {
+	i := sort.Search(len(q.evs), func(i int) bool { return q.evs[i].Time >= ev.Time })
+	for ; i < len(q.evs) && q.evs[i].Time == ev.Time; i++ {
+		if q.evs[i].Id == ev.Id {
+			q.evs = append(q.evs[:i], q.evs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+/* the last one of the lowest Gen among the events with the minimum time, as EventHeap */
+func (q *ListQueue) ExtractHead() *Event {
+	if len(q.evs) == 0 {
+		return nil
+	}
+	n := 1
+	for n < len(q.evs) && q.evs[n].Time == q.evs[0].Time {
+		n++
+	}
+	h := n - 1
+	for i := n - 2; i >= 0; i-- {
+		if q.evs[i].Gen < q.evs[h].Gen {
+			h = i
+		}
+	}
+	head := q.evs[h]
+	q.evs = append(q.evs[:h], q.evs[h+1:]...)
+	return &head
+}
+
+func (q *ListQueue) GetMinTime() Time {
+	if len(q.evs) == 0 {
+		return NOTIME
+	}
+	return q.evs[0].Time
+}
+
+func (q *ListQueue) IsEmpty() bool {
+	return len(q.evs) == 0
+}
+
+func (q *ListQueue) Count() int {
+	return len(q.evs)
+}
+
+func (q *ListQueue) Events() []Event {
+	return append([]Event(nil), q.evs...)
+}
+
+func (q *ListQueue) Print() {
+	for i := range q.evs {
+		fmt.Println("Event", q.evs[i].Id, "time", q.evs[i].Time)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * RANDOM NUMBER STREAMS
+ *
+ * every entity and every LP has its own stream of random numbers, so the
+ * numbers drawn by an event depend neither on the scheduling of the LPs
+ * nor on the placement of the entities. The streams are reversible: the
+ * kernel saves the state of the stream of an event that draws some
+ * numbers and, when the event is rolled back, restores it, so that the
+ * re-executed event draws the same numbers. The streams are generated by
+ * an rng.Source, MRG32k3a by default
+ */
+
+import (
+	list "container/list"
+	"fmt"
+	"github.com/jeffallen/go-warp/rng"
+	"os"
+)
+
+const DEFAULTSEED = 1
+
+/* the state of a stream before the draws of an event, implements Elem */
+type drawMark struct {
+	T     Time
+	Gen   int32
+	Rng   *rng.Rand
+	State []uint64
+}
+
+var (
+	DefaultSource rng.Source = rng.MRG32k3aStreams
+
+	entityStreams []*rng.Rand // entityStreams[e] is the stream of entity e
+	lpStreams     []*rng.Rand
+
+	ckptStreams [][]uint64 // the states read by Restart, see SetStreams
+)
+
+func (m drawMark) GetTime() Time {
+	return m.T
+}
+
+func (m drawMark) IsEqual(e Elem) bool {
+	m1 := e.(drawMark)
+	return m.T == m1.T && m.Rng == m1.Rng
+}
+
+/*
+ * creates the streams of the entities and of the LPs, it must be called
+ * after SetEntities (if the model has entities). Entity e gets stream e,
+ * the LPs the streams that follow; a zero seed means DEFAULTSEED and a nil
+ * src DefaultSource. After Restart the streams continue from the states
+ * saved in the checkpoint
+ */
+func SetStreams(seed int64, src rng.Source) {
+	if seed == 0 {
+		seed = DEFAULTSEED
+	}
+	if src == nil {
+		src = DefaultSource
+	}
+	s := rng.NewStreams(src, seed, len(entities)+Lpnum)
+	entityStreams, lpStreams = s[:len(entities)], s[len(entities):]
+
+	if ckptStreams == nil {
+		return
+	}
+	if len(ckptStreams) != len(s) {
+		fmt.Println("GO-WARP, ERROR: THE CHECKPOINT HAS", len(ckptStreams), "RANDOM STREAMS, NOT", len(s))
+		os.Exit(1)
+	}
+	for i := range s {
+		if err := s[i].SetState(ckptStreams[i]); err != nil {
+			fmt.Println("GO-WARP, ERROR: RESTORING THE RANDOM STREAM", i, "-", err)
+			os.Exit(1)
+		}
+	}
+	ckptStreams = nil
+}
+
+/*
+ * returns the stream of the entity that is executing the current event
+ * or, if the model has no entities, the stream of the LP. The numbers must
+ * be drawn only while the event is executed, the draws are undone if the
+ * event is rolled back
+ */
+func (l *LocalData) Rand() *rng.Rand {
+	if lpStreams == nil {
+		fmt.Println(l.IndexLP, "- GO-WARP, ERROR: THE RANDOM STREAMS ARE NOT SET")
+		os.Exit(1)
+	}
+	if entities != nil {
+		return entityStreams[l.entity]
+	}
+	return lpStreams[l.IndexLP]
+}
+
+/* the stream of entity e, to draw the initial events of the model */
+func EntityStream(e int) *rng.Rand {
+	return entityStreams[e]
+}
+
+/* the stream that can be used by ev, nil if there are no streams */
+func eventStream(ev *Event, l *LocalData) *rng.Rand {
+	if lpStreams == nil {
+		return nil
+	}
+	if entities != nil {
+		e := ev.Type.To
+		if e < 0 || e >= len(entityStreams) {
+			return nil // the EventManager reports the error
+		}
+		return entityStreams[e]
+	}
+	return lpStreams[l.IndexLP]
+}
+
+/* saves in l.drawState the state of the stream of ev before its execution */
+func startDraws(ev *Event, l *LocalData) *rng.Rand {
+	r := eventStream(ev, l)
+	if r != nil {
+		l.drawState = r.AppendState(l.drawState[:0])
+	}
+	return r
+}
+
+/* logs the state saved by startDraws if ev has drawn some numbers */
+func endDraws(ev *Event, r *rng.Rand, l *LocalData) {
+	if r == nil {
+		return
+	}
+	l.drawCheck = r.AppendState(l.drawCheck[:0])
+	for i := range l.drawCheck {
+		if l.drawCheck[i] != l.drawState[i] {
+			Insert(drawMark{ev.Time, l.gen, r, append([]uint64(nil), l.drawState...)}, l.DrawLog)
+			return
+		}
+	}
+}
+
+/* undoes the draws of the events after (t, gen), see rollback */
+func restoreStreams(t Time, gen int32, draws *list.List) {
+	for el := draws.Back(); el != nil; el = draws.Back() {
+		m := el.Value.(drawMark)
+		if !undone(m.T, m.Gen, t, gen) {
+			break
+		}
+		m.Rng.SetState(m.State)
+		draws.Remove(el)
+	}
+}
+
+/* the states of all the streams, for the checkpoints */
+func saveStreams() [][]uint64 {
+	var ret [][]uint64
+	for _, s := range [][]*rng.Rand{entityStreams, lpStreams} {
+		for _, r := range s {
+			ret = append(ret, r.AppendState(nil))
+		}
+	}
+	return ret
+}
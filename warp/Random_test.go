@@ -0,0 +1,75 @@
+package warp
+
+import (
+	"github.com/jeffallen/go-warp/rng"
+	"testing"
+)
+
+/*
+ * an entity that draws the delay and the receiver of its events from its
+ * stream: the chains never share an entity (the receiver of chain c is
+ * c + 16k), so the events of an entity are executed in the same order by
+ * both kernels
+ */
+type testDrawer struct {
+	N   int
+	Sum float64
+}
+
+func (d *testDrawer) Handle(ev *Event, l *LocalData) {
+	rng := l.Rand()
+	d.N++
+	d.Sum += rng.RandFloat()
+	to := ev.Type.To%16 + 16*int(rng.RandIntUniform(0, 3))
+	next := CreateEvent(0, ev.Time+1+Time(rng.RandIntUniform(0, 9)), Info{})
+	if err := NoticeEntity(next, to, l); err != nil {
+		panic(err)
+	}
+}
+
+func (d *testDrawer) Save() interface{} {
+	return *d
+}
+
+func (d *testDrawer) Restore(s interface{}) {
+	*d = s.(testDrawer)
+}
+
+func runDrawers(t *testing.T, p Partitioner, src rng.Source, seq bool) ([]TraceRecord, []testDrawer, [][]uint64) {
+	drawers := make([]testDrawer, testEntities)
+	ents := make([]Entity, testEntities)
+	for i := range ents {
+		ents[i] = &drawers[i]
+	}
+	cfg := Config{LPs: 4, EndTime: 300, Entities: ents, Partition: p, Seed: 42, Generator: src, Sequential: seq}
+	trace := traceRun(t, cfg, testEntityInit(16))
+	return trace, drawers, saveStreams()
+}
+
+/* the draws of the rolled back events are undone */
+func TestStreamRollback(t *testing.T) {
+	srcs := []rng.Source{nil, rng.PCGStreams, rng.XoshiroStreams}
+	for i, p := range []Partitioner{BlockPartition{testEntities, 4}, HashPartition{4}, RoundRobinPartition{4}} {
+		seq, final, seqStreams := runDrawers(t, p, srcs[i], true)
+
+		d := &testDelayer{links: make(map[[2]Pid]*testLink), seed: int64(i)}
+		sendHook = d.send
+		par, states, parStreams := runDrawers(t, p, srcs[i], false)
+		d.stop()
+
+		checkTrace(t, 4, seq, par)
+		for e := range final {
+			if states[e] != final[e] {
+				t.Fatalf("%T: entity %d has state %+v, want %+v", p, e, states[e], final[e])
+			}
+		}
+		for i := range seqStreams {
+			for k := range seqStreams[i] {
+				if parStreams[i][k] != seqStreams[i][k] {
+					t.Fatalf("%T: the random stream %d is in a different state", p, i)
+				}
+			}
+		}
+		t.Logf("%T: %d events, %d rollbacks", p, len(par), CollectStats().Rollbacks)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * HIGH-LEVEL RUN API
+ *
+ * Run creates the LPs, lets the model insert their initial events and
+ * executes them until the end of the simulation: it replaces the
+ * SimSetup / SimInitialize / Simulate sequence that every model had to
+ * write, including the goroutines and the wait for their termination.
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jeffallen/go-warp/rng"
+	"runtime"
+	"sync"
+)
+
+type Config struct {
+	LPs        int                           // number of LPs, 0 means one per CPU
+	EndTime    Time                          // the events with time >= EndTime are not executed
+	Handler    func(ev *Event, l *LocalData) // the EventManager of the model
+	Entities   []Entity                      // or the entities of the model, see Entity.go
+	Partition  Partitioner                   // maps the entities to the LPs, nil means BlockPartition
+	Policy     MigrationPolicy               // if not nil the entities are migrated between the LPs
+	Rebalance  Time                          // simulated time between two applications of Policy
+	Context    context.Context               // if not nil, cancels the simulation (or sets a deadline)
+	Seed       int64                         // seed of the random streams, see Random.go
+	Generator  rng.Source                    // generates the random streams, nil means DefaultSource
+	Sequential bool                          // run on the sequential reference kernel
+	Lookahead  Time                          // minimum delay of the events, see NoticeEvent
+
+	/* kernel tuning, see Sim.go; ignored by the sequential kernel */
+	Window       Time // limited optimism, 0 means no limit
+	GvtThreshold int  // length of the logs that starts a GVT evaluation, 0 means TOOLARGE
+	Queue        int  // implementation of the future events, QUEUEHEAP or QUEUELIST
+}
+
+type Result struct {
+	Stats *Stats
+	LPs   []*LocalData  // the local areas, with the final state of the LPs
+	Trace []TraceRecord // the executed events, only with the sequential kernel
+}
+
+/*
+ * runs a simulation: initFn is called for every LP, before the simulation
+ * starts and on the calling goroutine, to insert its initial events in
+ * FutureEvents. If the simulation is cancelled the partial result is
+ * returned together with the error of the context, if a LP fails (see
+ * LocalData.Fail) together with its error
+ */
+func Run(cfg Config, initFn func(l *LocalData) error) (*Result, error) {
+	if cfg.LPs == 0 {
+		cfg.LPs = runtime.NumCPU()
+	}
+	if cfg.LPs < 0 {
+		return nil, fmt.Errorf("GO-WARP: invalid number of LPs %d", cfg.LPs)
+	}
+	if cfg.EndTime <= 0 {
+		return nil, fmt.Errorf("GO-WARP: invalid end time %d", cfg.EndTime)
+	}
+	if cfg.Entities != nil {
+		if cfg.Handler != nil {
+			return nil, errors.New("GO-WARP: both an event handler and entities")
+		}
+		cfg.Handler = EntityManager
+	}
+	if cfg.Handler == nil {
+		return nil, errors.New("GO-WARP: no event handler")
+	}
+	if cfg.Window < 0 || cfg.GvtThreshold < 0 {
+		return nil, errors.New("GO-WARP: invalid window or GVT threshold")
+	}
+	if cfg.Lookahead < 0 {
+		return nil, fmt.Errorf("GO-WARP: invalid lookahead %d", cfg.Lookahead)
+	}
+	if _, err := NewQueue(cfg.Queue); err != nil {
+		return nil, err
+	}
+	if cfg.Policy != nil && (cfg.Entities == nil || cfg.Rebalance <= 0) {
+		return nil, errors.New("GO-WARP: the migration policy needs entities and a rebalancing interval")
+	}
+	ctx := cfg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res := &Result{LPs: make([]*LocalData, cfg.LPs)}
+
+	Window, GvtThreshold, Lookahead, Queue = cfg.Window, cfg.GvtThreshold, cfg.Lookahead, cfg.Queue
+	if GvtThreshold == 0 {
+		GvtThreshold = TOOLARGE
+	}
+
+	if cfg.Sequential {
+		SeqSetup(cfg.LPs, cfg.EndTime, cfg.Handler)
+	} else {
+		SimSetup(cfg.LPs, cfg.EndTime, cfg.Handler)
+	}
+	if cfg.Entities != nil {
+		SetEntities(cfg.Entities, cfg.Partition)
+	}
+	SetStreams(cfg.Seed, cfg.Generator)
+	for i := range res.LPs {
+		if cfg.Sequential {
+			res.LPs[i] = SeqInitialize(Pid(i))
+		} else {
+			res.LPs[i] = SimInitialize(Pid(i))
+		}
+		if initFn == nil {
+			continue
+		}
+		if err := initFn(res.LPs[i]); err != nil {
+			return nil, fmt.Errorf("GO-WARP: initializing LP %d: %v", i, err)
+		}
+	}
+
+	if cfg.Sequential {
+		res.Trace = SeqSimulate()
+		res.Stats = CollectStats()
+	} else if cfg.Policy != nil {
+		var err error
+		if res.Stats, err = runBalanced(ctx, res.LPs, cfg.Policy, cfg.Rebalance); err != nil {
+			return res, err
+		}
+	} else {
+		res.Stats = RunLPs(ctx, res.LPs)
+	}
+	if err := Failure(); err != nil {
+		return res, err
+	}
+	if res.Stats.Cancelled {
+		return res, ctx.Err()
+	}
+	return res, nil
+}
+
+/* the simulation is executed in slices, the entities are migrated between them */
+func runBalanced(ctx context.Context, lps []*LocalData, p MigrationPolicy, every Time) (*Stats, error) {
+	for h := every; ; h += every {
+		Resume(h)
+		s := RunLPs(ctx, lps)
+		if s.Cancelled || Horizon >= EndTime {
+			return s, nil
+		}
+		if _, err := Rebalance(p); err != nil {
+			return s, err
+		}
+	}
+}
+
+/*
+ * executes every LP on its own goroutine and waits for all of them, the
+ * simulation can be stopped (or given a wall clock deadline) with ctx.
+ * Returns the statistics, partial if the simulation has been cancelled
+ */
+func RunLPs(ctx context.Context, lps []*LocalData) *Stats {
+	var wg sync.WaitGroup
+
+	for _, data := range lps {
+		if data == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(d *LocalData) {
+			Simulate(ctx, d)
+			wg.Done()
+		}(data)
+	}
+	wg.Wait()
+
+	return CollectStats()
+}
@@ -0,0 +1,181 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * SCHEDULING
+ *
+ * the model-facing API over NoticeEvent: Schedule sends an event after a
+ * delay and returns a handle, Cancel retracts a pending event, e.g. a
+ * timeout that is no longer needed. A cancelled event is annihilated as
+ * if its sender had been rolled back: Cancel sends its anti-message, and
+ * the retraction is logged at the time of the cancelling event, so that if
+ * that event is rolled back the cancelled event is sent again (see
+ * rollback). On the sequential kernel the cancelled events are skipped.
+ */
+
+import (
+	"container/list"
+	"errors"
+)
+
+var (
+	ErrPast       = errors.New("GO-WARP: the event would be in the past")
+	ErrLookahead  = errors.New("GO-WARP: the delay of the event is less than the lookahead")
+	ErrTime       = errors.New("GO-WARP: the event time is out of range")
+	ErrTarget     = errors.New("GO-WARP: unknown receiver of the event")
+	ErrFlag       = errors.New("GO-WARP: the flag of the event is reserved to the kernel")
+	ErrNotPending = errors.New("GO-WARP: the event is not pending")
+)
+
+/*
+ * identifies a scheduled event. It is a value, so it can be part of the
+ * state of an entity that is saved and restored; the zero EventHandle is
+ * not a scheduled event
+ */
+type EventHandle struct {
+	ev       Event
+	receiver Pid
+}
+
+/* the time of the scheduled event */
+func (h EventHandle) Time() Time {
+	return h.ev.Time
+}
+
+/* the Id of the scheduled event, 0 for the zero EventHandle */
+func (h EventHandle) Id() int64 {
+	return h.ev.Id
+}
+
+/*
+ * the entity that is executing the current event or, if the model has no
+ * entities, the LP: the target of the events to self, as the timers
+ */
+func (l *LocalData) Self() int {
+	if entities != nil {
+		return l.entity
+	}
+	return int(l.IndexLP)
+}
+
+/*
+ * sends an event with the payload to target delay after the current
+ * simulated time, it must be called while an event is executed. The target
+ * is an entity or, if the model has no entities, an LP; From and To of the
+ * payload are set by the kernel for the entities, left as they are for the
+ * LPs. The delay is checked by NoticeEvent, that also tells the rules of
+ * the zero delays
+ */
+func (l *LocalData) Schedule(delay Time, target int, payload Info) (EventHandle, error) {
+	if delay < 0 {
+		return EventHandle{}, ErrPast
+	}
+	t := l.SimTime + delay
+	if t < l.SimTime {
+		return EventHandle{}, ErrTime
+	}
+
+	var receiver Pid
+	if entities != nil {
+		if target < 0 || target >= len(entities) {
+			return EventHandle{}, ErrTarget
+		}
+		receiver = partition.LP(target)
+	} else {
+		if target < 0 || target >= Lpnum {
+			return EventHandle{}, ErrTarget
+		}
+		receiver = Pid(target)
+	}
+
+	ev := CreateEvent(0, t, payload)
+	var err error
+	if entities != nil {
+		err = NoticeEntity(ev, target, l)
+	} else {
+		err = NoticeEvent(ev, receiver, l)
+	}
+	if err != nil {
+		return EventHandle{}, err
+	}
+	return EventHandle{*ev, receiver}, nil
+}
+
+/*
+ * retracts a scheduled event, that must be after the current simulated
+ * time: an event at the current time may have already been executed.
+ * Cancelling an event twice returns ErrNotPending
+ */
+func (l *LocalData) Cancel(h EventHandle) error {
+	if h.ev.Id <= 0 || h.ev.Time <= l.SimTime {
+		return ErrNotPending
+	}
+	if Sequential {
+		return seqCancel(h.ev.Id)
+	}
+	for el := l.Retractions.Front(); el != nil; el = el.Next() {
+		if el.Value.(TimedMessage).M.Ev.Id == h.ev.Id {
+			return ErrNotPending
+		}
+	}
+
+	msg := CreateMessage(l.IndexLP, h.receiver, h.ev)
+	Insert(TimedMessage{*msg, l.SimTime, l.gen}, l.Retractions)
+
+	anti := createAntiMessage(msg)
+	if h.receiver == l.IndexLP {
+		annihilate(&anti.Ev, l)
+	} else {
+		sendMessage(anti, l)
+		l.Stats.AntiSent++
+	}
+	return nil
+}
+
+/* the cancellations after (t, gen) are undone: the events are sent again */
+func undoRetractions(t Time, gen int32, data *LocalData) {
+	for el := data.Retractions.Back(); el != nil; el = data.Retractions.Back() {
+		tm := el.Value.(TimedMessage)
+		if !undone(tm.T, tm.Gen, t, gen) {
+			break
+		}
+		data.Retractions.Remove(el)
+
+		msg := tm.M
+		if msg.Receiver != data.IndexLP {
+			sendMessage(&msg, data)
+		} else if !checkAntimsg(&msg.Ev, data) {
+			data.insertEvent(&msg.Ev)
+		}
+	}
+}
+
+/*
+ * the retractions committed before t are kept until their events are
+ * before t too, so that Cancel can tell an event already cancelled
+ */
+func fossilRetractions(t Time, retractions *list.List) {
+	for el := retractions.Front(); el != nil; {
+		tm := el.Value.(TimedMessage)
+		if tm.T > t {
+			break
+		}
+		next := el.Next()
+		if tm.M.Ev.Time <= t {
+			retractions.Remove(el)
+		}
+		el = next
+	}
+}
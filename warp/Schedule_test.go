@@ -0,0 +1,231 @@
+package warp
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+/*
+ * an entity with a timeout: every ping restarts the timeout of its
+ * receiver and is forwarded, the timeout fires if no ping arrives in
+ * testTimeout. The timeout is executed by the next entity, so that with
+ * the round robin partition it is cancelled by another LP. The pings have
+ * even times and the timeouts odd times, so the events of an entity with
+ * the same time commute. Bad counts the calls of Schedule and Cancel that
+ * did not return the expected error
+ */
+const (
+	testTimeout = 15
+	testFired   = 1 << 30
+)
+
+type testTimer struct {
+	Pings, Fired, Cancelled, Bad int
+	Timeout                      EventHandle // its Id depends on the kernel
+}
+
+func (e *testTimer) Handle(ev *Event, l *LocalData) {
+	if ev.Type.Flag == testFired {
+		e.Fired++
+		return
+	}
+	e.Pings++
+
+	if l.Cancel(e.Timeout) == nil {
+		e.Cancelled++
+		if l.Cancel(e.Timeout) != ErrNotPending {
+			e.Bad++
+		}
+	}
+	var err error
+	if e.Timeout, err = l.Schedule(testTimeout, (l.Self()+1)%testEntities, Info{Flag: testFired}); err != nil {
+		e.Bad++
+	}
+
+	h := testHash(ev)
+	if _, err := l.Schedule(2*Time(1+h>>8%5), int(h%testEntities), Info{Flag: ev.Type.Flag + 1}); err != nil {
+		e.Bad++
+	}
+	if _, err := l.Schedule(-1, l.Self(), Info{}); err != ErrPast {
+		e.Bad++
+	}
+	if _, err := l.Schedule(1, testEntities, Info{}); err != ErrTarget {
+		e.Bad++
+	}
+	if _, err := l.Schedule(1, l.Self(), Info{Flag: ANTIMSG}); err != ErrFlag {
+		e.Bad++
+	}
+}
+
+func (e *testTimer) Save() interface{} {
+	return *e
+}
+
+func (e *testTimer) Restore(s interface{}) {
+	*e = s.(testTimer)
+}
+
+/* the state without the handle, that is not comparable */
+func (e *testTimer) counts() [4]int {
+	return [4]int{e.Pings, e.Fired, e.Cancelled, e.Bad}
+}
+
+func runTimers(t *testing.T, cfg Config) ([]TraceRecord, []testTimer) {
+	timers := make([]testTimer, testEntities)
+	ents := make([]Entity, testEntities)
+	for i := range ents {
+		ents[i] = &timers[i]
+	}
+	cfg.EndTime, cfg.Entities = 300, ents
+	return traceRun(t, cfg, testPings), timers
+}
+
+/* 16 chains of events, at even times */
+func testPings(l *LocalData) error {
+	for c := 0; c < 16; c++ {
+		e := c * 5 % testEntities
+		if EntityLP(e) == l.IndexLP {
+			l.NewEvent(CreateEvent(0, Time(2*(c%4)), Info{From: e, To: e, Flag: int32(c+1) << 16}))
+		}
+	}
+	return nil
+}
+
+/* the cancelled timeouts are never executed, also when the cancellations are rolled back */
+func TestSchedule(t *testing.T) {
+	for i, p := range []Partitioner{BlockPartition{testEntities, 4}, RoundRobinPartition{4}} {
+		seq, final := runTimers(t, Config{LPs: 4, Partition: p, Sequential: true})
+		var s testTimer
+		for _, e := range final {
+			s.Pings, s.Fired, s.Cancelled, s.Bad = s.Pings+e.Pings, s.Fired+e.Fired, s.Cancelled+e.Cancelled, s.Bad+e.Bad
+		}
+		if s.Bad != 0 || s.Fired == 0 || s.Cancelled == 0 || s.Pings+s.Fired != len(seq) {
+			t.Fatalf("%T: %+v, %d events", p, s, len(seq))
+		}
+
+		d := &testDelayer{links: make(map[[2]Pid]*testLink), seed: int64(i)}
+		sendHook = d.send
+		par, states := runTimers(t, Config{LPs: 4, Partition: p})
+		d.stop()
+
+		checkTrace(t, 4, seq, par)
+		for e := range final {
+			if states[e].counts() != final[e].counts() {
+				t.Fatalf("%T: entity %d has counts %v, want %v", p, e, states[e].counts(), final[e].counts())
+			}
+		}
+		t.Logf("%T: %d pings, %d fired, %d cancelled, %d rollbacks", p, s.Pings, s.Fired, s.Cancelled, CollectStats().Rollbacks)
+	}
+}
+
+/*
+ * a stateful entity that sends a third of its events with zero delay,
+ * the others after at least Lookahead. Each event carries the virtual
+ * time (time, gen) of its sender in Data. H folds the events in the order
+ * they are executed, the events with the same virtual time (that commute)
+ * are summed in Acc first. Bad counts the events in the past or closer
+ * than Lookahead that have not been refused, the events that are not after
+ * their senders and the events executed out of order
+ */
+type testZero struct {
+	N, Zero, Bad int
+	H, Acc       uint64
+	T            Time
+	Gen          int32
+}
+
+func (z *testZero) Handle(ev *Event, l *LocalData) {
+	z.N++
+	if ev.Time < z.T || ev.Time == z.T && ev.Gen < z.Gen {
+		z.Bad++
+	}
+	if ev.Time != z.T || ev.Gen != z.Gen {
+		z.H = (z.H^z.Acc)*1099511628211 + 1
+		z.Acc, z.T, z.Gen = 0, ev.Time, ev.Gen
+	}
+	z.Acc += uint64(testHash(ev))
+	if len(ev.Type.Data) == 12 {
+		t, gen := Time(binary.LittleEndian.Uint64(ev.Type.Data)), int32(binary.LittleEndian.Uint32(ev.Type.Data[8:]))
+		if ev.Time < t || ev.Time == t && ev.Gen <= gen {
+			z.Bad++
+		}
+	}
+	if ev.Time > 0 && NoticeEntity(CreateEvent(0, ev.Time-1, Info{}), l.Self(), l) != ErrPast {
+		z.Bad++
+	}
+
+	sender := make([]byte, 12)
+	binary.LittleEndian.PutUint64(sender, uint64(ev.Time))
+	binary.LittleEndian.PutUint32(sender[8:], uint32(ev.Gen))
+	h := testHash(ev)
+	d := Time(h >> 8 % 4)
+	if h%3 == 0 {
+		d = 0
+	}
+	err := NoticeEntity(CreateEvent(0, ev.Time+d, Info{Flag: ev.Type.Flag + 1, Data: sender}), int(h%testEntities), l)
+	if d >= Lookahead {
+		if err != nil {
+			z.Bad++
+		}
+		if d == 0 {
+			z.Zero++
+		}
+		return
+	}
+	if err != ErrLookahead {
+		z.Bad++
+	}
+	if err := NoticeEntity(CreateEvent(0, ev.Time+Lookahead, Info{Flag: ev.Type.Flag + 1, Data: sender}), int(h%testEntities), l); err != nil {
+		panic(err)
+	}
+}
+
+func (z *testZero) Save() interface{} {
+	return *z
+}
+
+func (z *testZero) Restore(s interface{}) {
+	*z = s.(testZero)
+}
+
+/*
+ * the zero-delay events are executed after their senders and in the same
+ * order by both the kernels; the events too close are refused
+ */
+func TestZeroDelay(t *testing.T) {
+	for _, lookahead := range []Time{0, 2} {
+		var final []testZero
+		var seq []TraceRecord
+		for _, sequential := range []bool{true, false} {
+			zs := make([]testZero, testEntities)
+			ents := make([]Entity, testEntities)
+			for i := range ents {
+				ents[i] = &zs[i]
+			}
+			cfg := Config{LPs: 4, EndTime: 300, Entities: ents, Partition: RoundRobinPartition{4}, Sequential: sequential, Lookahead: lookahead, Window: 4}
+			trace := traceRun(t, cfg, testPings)
+
+			var s testZero
+			for _, z := range zs {
+				s.N, s.Zero, s.Bad = s.N+z.N, s.Zero+z.Zero, s.Bad+z.Bad
+			}
+			if s.Bad != 0 || s.N != len(trace) || (lookahead == 0) != (s.Zero > 0) {
+				t.Fatalf("lookahead %d, sequential %v: %+v, %d events", lookahead, sequential, s, len(trace))
+			}
+			if sequential {
+				seq, final = trace, zs
+				continue
+			}
+			checkTrace(t, 4, seq, trace)
+			for e := range final {
+				if zs[e] != final[e] {
+					t.Fatalf("lookahead %d: entity %d has state %+v, want %+v", lookahead, e, zs[e], final[e])
+				}
+			}
+			t.Logf("lookahead %d: %d events, %d with zero delay, %d rollbacks", lookahead, s.N, s.Zero, CollectStats().Rollbacks)
+		}
+	}
+	if _, err := Run(Config{LPs: 1, EndTime: 10, Handler: testModel, Lookahead: -1}, testInit(1)); err == nil {
+		t.Fatal("negative lookahead accepted")
+	}
+}
@@ -0,0 +1,181 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * SEQUENTIAL REFERENCE KERNEL
+ *
+ * runs the same EventManager / NoticeEvent API of the Time Warp kernel
+ * using a single global pending event set and no rollbacks. The events
+ * are executed in the order of their virtual time (time, gen), as in
+ * the parallel kernel, then by receiver LP and event id. The resulting
+ * trace is the reference against which the committed events of a
+ * parallel run are verified.
+ */
+
+import (
+	"container/heap"
+)
+
+type seqItem struct {
+	lp  Pid
+	ev  Event
+	seq uint64 // insertion order, breaks the remaining ties
+}
+
+type seqQueue []seqItem
+
+var (
+	Sequential bool // true if the simulation runs on the sequential kernel
+
+	seqPending   seqQueue
+	seqCount     uint64
+	seqCancelled map[int64]bool // the Ids of the cancelled events, see Schedule.go
+	seqCommitted Time           // the events up to seqCommitted have been committed
+)
+
+/* seqQueue implements heap.Interface */
+func (q seqQueue) Len() int { return len(q) }
+
+func (q seqQueue) Less(i, j int) bool {
+	a, b := &q[i], &q[j]
+	if a.ev.Time != b.ev.Time {
+		return a.ev.Time < b.ev.Time
+	}
+	if a.ev.Gen != b.ev.Gen {
+		return a.ev.Gen < b.ev.Gen
+	}
+	if a.lp != b.lp {
+		return a.lp < b.lp
+	}
+	if a.ev.Id != b.ev.Id {
+		return a.ev.Id < b.ev.Id
+	}
+	return a.seq < b.seq
+}
+
+func (q seqQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *seqQueue) Push(x interface{}) { *q = append(*q, x.(seqItem)) }
+
+func (q *seqQueue) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
+
+func SeqSetup(lpn int, simt Time, f func(ev *Event, l *LocalData)) {
+	GvtSetup(lpn)
+	SharedSetup(lpn, simt, f)
+
+	Sequential = true
+	seqPending = make(seqQueue, 0, HEAPSIZE)
+	seqCount = 0
+	seqCancelled = make(map[int64]bool)
+	seqCommitted = -1
+}
+
+/*
+ * creates the local area of an LP. As in the parallel kernel the initial
+ * events are inserted by the model in FutureEvents, SeqSimulate moves
+ * them in the global pending event set
+ */
+func SeqInitialize(i Pid) *LocalData {
+	data := Initialize(i)
+	lpData[i] = data
+	setState(i, LPRUNNING)
+
+	return data
+}
+
+/*
+ * runs the whole simulation on the calling goroutine and returns the
+ * trace of the executed events, in execution order. It stops after the
+ * event whose handler calls Fail, that is not committed
+ */
+func SeqSimulate() []TraceRecord {
+	var trace []TraceRecord
+
+	for i, data := range lpData {
+		if data == nil {
+			continue
+		}
+		for ev := data.FutureEvents.ExtractHead(); ev != nil; ev = data.FutureEvents.ExtractHead() {
+			seqSchedule(ev, Pid(i))
+		}
+	}
+
+	for seqPending.Len() > 0 {
+		if seqPending[0].ev.Time >= EndTime {
+			break
+		}
+		it := heap.Pop(&seqPending).(seqItem)
+		if seqCancelled[it.ev.Id] {
+			delete(seqCancelled, it.ev.Id)
+			continue
+		}
+
+		data := lpData[it.lp]
+		if data == nil {
+			continue
+		}
+		data.SimTime = it.ev.Time
+		data.Gvt = it.ev.Time
+		seqCommitted = it.ev.Time - 1
+		data.gen = it.ev.Gen
+		data.N_PROCESSED++
+		data.Stats.Processed++
+
+		EventManager(&it.ev, data)
+		if Failure() != nil {
+			break
+		}
+		data.Stats.Committed++
+		loadCommitted(&it.ev)
+		commitEntity(&it.ev)
+
+		publishMetrics(data, false)
+
+		rec := TraceRecord{it.lp, it.ev}
+		trace = append(trace, rec)
+		if Tracer != nil {
+			Tracer.Write(&rec)
+		}
+	}
+
+	seqCommitted = MAXTIME
+	for i, data := range lpData {
+		if data != nil {
+			publishMetrics(data, true)
+		}
+		setState(Pid(i), LPSTOPPED)
+	}
+
+	return trace
+}
+
+func seqSchedule(ev *Event, receiver Pid) {
+	heap.Push(&seqPending, seqItem{receiver, *ev, seqCount})
+	seqCount++
+}
+
+/* the cancelled event is skipped when it reaches the head of the pending event set */
+func seqCancel(id int64) error {
+	if seqCancelled[id] {
+		return ErrNotPending
+	}
+	seqCancelled[id] = true
+	return nil
+}
@@ -14,33 +14,72 @@
 package warp
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
+/*
+ * the variables shared by the LPs are either read-only while the LPs are
+ * running or owned by a single LP (N_rollback[i], lpData[i]); the state
+ * of the LPs is read by all of them and is accessed atomically
+ */
 var (
 	Lpnum        int
-	N_gvt        int
-	State        []int8
-	N_rollback   []int
+	N_gvt        int // completed GVT evaluations, updated under gvtlock
+	lpState      []int32
+	N_rollback   []int   // N_rollback[i] is updated by LP i only
+	lpCommitted  []int32 // lpCommitted[i] is updated by LP i only, see CommittedTime
 	EventManager func(ev *Event, l *LocalData)
 	EndTime      Time
 
 	StartTime time.Time
+
+	lpData []*LocalData // the local areas of the initialized LPs
+
+	Quiet bool // if true the setup of a simulation is not printed
 )
 
 func SharedSetup(lpn int, simt Time, f func(ev *Event, l *LocalData)) {
 	Lpnum = lpn
 	EndTime = simt
+	Horizon = simt
 	N_gvt = 0
-	State = make([]int8, lpn)
+	lpState = make([]int32, lpn)
 	N_rollback = make([]int, lpn)
 	for i := 0; i < lpn; i++ {
-		State[i] = LPNOTSTART
+		setState(Pid(i), LPNOTSTART)
 		N_rollback[i] = 0
 	}
+	lpCommitted = make([]int32, lpn)
+	for i := range lpCommitted {
+		lpCommitted[i] = -1
+	}
 	EventManager = f
+	Sequential = false
+	lpData = make([]*LocalData, lpn)
+	entities, partition, entityLoad, ckptPartition = nil, nil, nil, nil
+	entityStreams, lpStreams, ckptStreams = nil, nil, nil
+	nMigrations = 0
+	failCtx, failCancel = context.WithCancelCause(context.Background())
+	metricsSetup(lpn)
 
-	fmt.Println("SETUP COMPLETED: lpn =", Lpnum, "EndTime =", EndTime)
+	if !Quiet {
+		fmt.Println("SETUP COMPLETED: lpn =", Lpnum, "EndTime =", EndTime)
+	}
 	StartTime = time.Now()
 }
+
+func getState(i Pid) int32 {
+	return atomic.LoadInt32(&lpState[i])
+}
+
+func setState(i Pid, s int32) {
+	atomic.StoreInt32(&lpState[i], s)
+}
+
+/* returns the state of LP i, it can be called while the simulation is running */
+func LPState(i Pid) int {
+	return int(getState(i))
+}
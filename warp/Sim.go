@@ -19,12 +19,29 @@ package warp
  */
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"runtime"
+	"sync/atomic"
 )
 
 const TOOFAR = 25 // limited optimism synchronization: sets how far from the GVT a LP can go
 
+var (
+	/*
+	 * limited optimism: a LP does not execute the events after GVT + Window
+	 * (e.g. TOOFAR) and waits for a new GVT; 0 means no limit
+	 */
+	Window Time
+
+	/* a GVT evaluation is started when a log of a LP is longer than GvtThreshold */
+	GvtThreshold int = TOOLARGE
+
+	/* the minimum delay of the events noticed by the model, see NoticeEvent */
+	Lookahead Time
+)
+
 func SimSetup(lpn int, simt Time, f func(ev *Event, l *LocalData)) {
 	AllocateChans(lpn)
 	GvtSetup(lpn)
@@ -39,27 +56,55 @@ func SimInitialize(i Pid) *LocalData {
 	var data *LocalData
 
 	data = Initialize(i)
-	State[i] = LPRUNNING
+	lpData[i] = data
+	setState(i, LPRUNNING)
 
 	return data
 }
 
-func Simulate(data *LocalData) {
+/*
+ * executes the LP until the end of the simulation or until ctx is done or
+ * a LP fails: in that case all the LPs stop and only the events up to the
+ * last GVT are committed
+ */
+func Simulate(ctx context.Context, data *LocalData) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(failCtx, cancel)()
+	data.done = ctx.Done()
 
 	for {
 
-		if State[data.IndexLP] == LPSTOPPED {
-			State[data.IndexLP] = LPSTOPPED
+		if getState(data.IndexLP) == LPSTOPPED {
+			commitEvents(MAXTIME, data) // the simulation is over, all the processed events are committed
+			stopLP(data)
 			return
 		}
 
+		select {
+		case <-data.done:
+			t := committed(gvtCancel())
+			commitEvents(t, data)
+			restoreStates(t+1, 0, data.StateLog) // the entities go back to the committed state
+			restoreStreams(t+1, 0, data.DrawLog)
+			setState(data.IndexLP, LPSTOPPED)
+			stopLP(data)
+			return
+		default:
+		}
+
 		receiveAll(data)
 
-		if data.SimTime >= EndTime {
+		if data.SimTime >= Horizon {
 			goIdle(data)
 		}
 
-		manageEvent(data)
+		if Window > 0 && beyondWindow(data) {
+			waitWindow(data)
+		} else {
+			manageEvent(data)
+		}
+		publishMetrics(data, false)
 
 		if data.GvtFlag && !CheckEvaluation() {
 			t := GetGvt()
@@ -71,20 +116,62 @@ func Simulate(data *LocalData) {
 	}
 }
 
+func stopLP(data *LocalData) {
+	data.ProcessedEvents.Init()
+	data.MsgSent.Init()
+	data.StateLog.Init()
+	data.DrawLog.Init()
+	publishMetrics(data, true) // the Retractions are kept for the next slice, see quiesce
+}
+
 /*
  * creates and sends a message to the receiver that contains the event to be
- * noticed. Saves the related anti-message in sender local area
+ * noticed, with a new Id. Saves the related anti-message in sender local area.
+ *
+ * The event is not sent, and an error is returned, if it is before the
+ * current simulated time (ErrPast), closer than Lookahead (ErrLookahead),
+ * at MAXTIME or beyond (ErrTime) or if its flag is reserved (ErrFlag).
+ * With Lookahead 0 an event can have the current time (a zero-delay
+ * event): it is executed after the event that noticed it, by both the
+ * kernels, since its Gen is one more. The order of the other events with
+ * the same (time, gen) and receiver is not defined (the sequential kernel
+ * executes them by LP and Id, the parallel one as they arrive), a model
+ * whose result must not depend on the kernel must make them commute
  */
-func NoticeEvent(ev *Event, receiver Pid, data *LocalData) {
+func NoticeEvent(ev *Event, receiver Pid, data *LocalData) error {
 	var tm TimedMessage
 	var msg *Message
 
+	switch {
+	case ev.Time < data.SimTime:
+		return ErrPast
+	case ev.Time-data.SimTime < Lookahead:
+		return ErrLookahead
+	case ev.Time >= MAXTIME:
+		return ErrTime
+	case ev.Type.Flag == ANTIMSG:
+		return ErrFlag
+	}
+
+	ev.Id = data.newId()
+	ev.Sender = data.IndexLP
+	ev.Gen = 0
+	if ev.Time == data.SimTime {
+		ev.Gen = data.gen + 1
+	}
+	data.Stats.Sent[receiver]++
+
+	if Sequential {
+		seqSchedule(ev, receiver)
+		return nil
+	}
+
 	/* creating the message to send */
 	msg = CreateMessage(data.IndexLP, receiver, *ev)
 
 	if receiver == data.IndexLP {
 		if !data.FutureEvents.Insert(ev) {
-			fmt.Println("GO-WARP, ERROR: THE HEAP IS FULL -", len(data.FutureEvents))
+			fmt.Println("GO-WARP, ERROR: THE QUEUE IS FULL -", data.FutureEvents.Count())
 			fmt.Println(data.IndexLP, "- GO-WARP, ERROR: EVENT NOT INSERTED!")
 			os.Exit(1)
 		}
@@ -93,12 +180,13 @@ func NoticeEvent(ev *Event, receiver Pid, data *LocalData) {
 		sendMessage(msg, data)
 	}
 
-	tm = TimedMessage{*msg, data.SimTime}
+	tm = TimedMessage{*msg, data.SimTime, data.gen}
 
 	size := Insert(tm, data.MsgSent)
-	if size > TOOLARGE && State[data.IndexLP] != LPEVALGVT {
+	if size > GvtThreshold && getState(data.IndexLP) != LPEVALGVT {
 		ask4NewGvt(data)
 	}
+	return nil
 }
 
 func receiveAll(data *LocalData) {
@@ -117,28 +205,36 @@ Loop:
 func manageMessage(data *LocalData, msg *Message) {
 	switch msg.Ev.Time {
 	case GVTEVAL:
-		if State[data.IndexLP] != LPSTOPPED {
-			evaluateLocalMin(data)
+		if getState(data.IndexLP) != LPSTOPPED {
+			evaluateLocalMin(data, int32(msg.Ev.Id)) // the Id is the round identifier
 		}
 
 	case ABORTMSG:
-		State[data.IndexLP] = LPSTOPPED
+		setState(data.IndexLP, LPSTOPPED)
 
 	case ACK:
+		data.changed = true
 		gotAck(msg, data)
 
 	default:
+		data.changed = true
 		sendAck(msg, data)
 
+		if forwardEntity(msg, data) {
+			return
+		}
+
 		if checkAntimsg(&msg.Ev, data) {
 			return
 		}
 		if msg.Ev.Type.Flag == ANTIMSG { // anti-message
+			data.Stats.AntiReceived++
 			annihilate(&(msg.Ev), data)
 			return
 		}
-		if msg.Ev.Time < data.SimTime { // straggler message
-			rollback(msg.Ev.Time, data)
+		if straggler(&msg.Ev, data) {
+			/* the executed events with the same time and Gen are not undone, their order is not defined */
+			rollback(msg.Ev.Time, msg.Ev.Gen+1, data)
 		}
 
 		/* finally we can insert the message in the heap */
@@ -155,19 +251,15 @@ func manageMessage(data *LocalData, msg *Message) {
 func manageEvent(data *LocalData) bool {
 	var ev *Event
 
-	data.N_PROCESSED++
-
 	t := data.FutureEvents.GetMinTime()
 
-	if t >= EndTime {
-		goIdle(data)
+	if t >= Horizon || t == NOTIME {
+		goIdle(data) // nothing to do before the Horizon
 		return false
 	} else if t > data.SimTime {
 		data.SimTime = t
 	} else if t == data.SimTime {
 		/* OK, DN */
-	} else if t == NOTIME {
-		/* heap empty */
 	} else {
 		fmt.Println(data.IndexLP, "- GO-WARP, ERROR: PROCESSING AN EVENT IN THE PAST!")
 		os.Exit(1)
@@ -177,90 +269,153 @@ func manageEvent(data *LocalData) bool {
 	if ev == nil {
 		return false
 	}
+	data.N_PROCESSED++
+	data.changed = true
 
+	data.gen = ev.Gen
+	r := startDraws(ev, data)
+	data.executing = true
 	EventManager(ev, data)
+	data.executing = false
+	endDraws(ev, r, data)
+	data.Stats.Processed++
 
 	size := Insert(*ev, data.ProcessedEvents)
-	if size > TOOLARGE && State[data.IndexLP] != LPEVALGVT {
+	if size > GvtThreshold && getState(data.IndexLP) != LPEVALGVT {
 		ask4NewGvt(data)
 	}
 
 	return true
 }
 
-func rollback(t Time, data *LocalData) {
-	data.SimTime = t
+/*
+ * the virtual time of an event is the pair (Time, Gen): an entry of a log
+ * with time T, written by an event with generation g, is undone by the
+ * rollback to (t, gen) if it is not before it
+ */
+func undone(T Time, g int32, t Time, gen int32) bool {
+	return T > t || T == t && g >= gen
+}
 
+/* true if the LP has executed an event after ev */
+func straggler(ev *Event, data *LocalData) bool {
+	if ev.Time < data.SimTime {
+		return true
+	}
 	el := data.ProcessedEvents.Back()
-Loop:
-	for el != nil {
+	return el != nil && undone(el.Value.(Event).Time, el.Value.(Event).Gen, ev.Time, ev.Gen+1)
+}
+
+/*
+ * undoes the executed events that are not before (t, gen): the rollback to
+ * (t, 0) undoes all the events with time >= t
+ */
+func rollback(t Time, gen int32, data *LocalData) {
+	data.SimTime = t
+
+	/*
+	 * the undone events are removed before the anti-messages are sent: the
+	 * ones of the zero-delay events sent to the LP itself must find them in
+	 * FutureEvents and not start another rollback
+	 */
+	for el := data.ProcessedEvents.Back(); el != nil; el = data.ProcessedEvents.Back() {
 		e := el.Value.(Event)
-		el = el.Prev()
-		if e.Time >= data.SimTime {
-			if !data.FutureEvents.Insert(&e) {
-				fmt.Println("GO-WARP, ERROR: INSERTING A PROCESSED EVENT!")
-			}
-			data.N_PROCESSED--
-		} else {
-			break Loop
+		if !undone(e.Time, e.Gen, t, gen) {
+			break
 		}
+		data.ProcessedEvents.Remove(el)
+		if !data.FutureEvents.Insert(&e) {
+			fmt.Println("GO-WARP, ERROR: INSERTING A PROCESSED EVENT!")
+		}
+		data.N_PROCESSED--
+		data.Stats.RolledBack++
+		loadRolledBack(&e)
 	}
 
-	el = data.MsgSent.Back()
-Loop1:
-	for el != nil {
+	undoRetractions(t, gen, data)
+
+	for el := data.MsgSent.Back(); el != nil; el = data.MsgSent.Back() {
 		mp := el.Value.(TimedMessage)
-		if mp.GetTime() >= data.SimTime {
-			el = el.Prev()
-			anti := createAntiMessage(&mp.M)
+		if !undone(mp.T, mp.Gen, t, gen) {
+			break
+		}
+		data.MsgSent.Remove(el)
+		anti := createAntiMessage(&mp.M)
 
-			if mp.M.Receiver == data.IndexLP {
-				annihilate(&(anti.Ev), data)
-			} else {
-				sendMessage(anti, data)
-			}
+		if mp.M.Receiver == data.IndexLP {
+			annihilate(&(anti.Ev), data)
 		} else {
-			break Loop1
+			sendMessage(anti, data)
+			data.Stats.AntiSent++
 		}
 	}
 
-	DeleteAfter(data.SimTime, data.ProcessedEvents)
-	DeleteAfter(data.SimTime, data.MsgSent)
+	restoreStates(t, gen, data.StateLog)
+	restoreStreams(t, gen, data.DrawLog)
 
 	N_rollback[data.IndexLP]++
+	data.Stats.Rollbacks++
 
 }
 
 func sendMessage(msg *Message, data *LocalData) {
-	tm := TimedMessage{*msg, data.SimTime}
+	tm := TimedMessage{*msg, data.SimTime, data.gen}
 	size := Insert(tm, data.OutgoingMsg)
 
-	if size > TOOLARGE {
-		if State[data.IndexLP] != LPEVALGVT {
+	if size > GvtThreshold {
+		if getState(data.IndexLP) != LPEVALGVT {
 			ask4NewGvt(data)
 		}
 	}
 	Send(msg)
 }
 
+/* the next event of the LP is before the Horizon but beyond the window */
+func beyondWindow(data *LocalData) bool {
+	t := data.FutureEvents.GetMinTime()
+	return t != NOTIME && t < Horizon && t > data.Gvt+Window
+}
+
+/*
+ * the LP cannot block as in goIdle: the new GVT is not notified by a
+ * message, so it asks for an evaluation and yields, the main loop
+ * receives the messages and sets the GVT
+ */
+func waitWindow(data *LocalData) {
+	if !data.GvtFlag && !CheckEvaluation() {
+		ask4NewGvt(data)
+	}
+	runtime.Gosched()
+}
+
+/*
+ * the LP has no events before the Horizon and blocks until a message
+ * arrives. If its state has changed since its last local minimum it asks
+ * for a GVT evaluation: the simulation is over when the GVT reaches the
+ * Horizon
+ */
 func goIdle(data *LocalData) {
-	if State[data.IndexLP] == LPSTOPPED {
+	if getState(data.IndexLP) == LPSTOPPED {
 		return
 	}
 
-	State[data.IndexLP] = LPIDLE
-	term := checkAllIdle()
-	if term {
-		killall(data)
-		State[data.IndexLP] = LPSTOPPED
-	} else {
-		m := BlockingReceive(data.IndexLP) // the process blocks indefinitively
+	setState(data.IndexLP, LPIDLE)
+	if data.changed && !RequestEvaluation() {
+		ask4NewGvt(data)
+	}
+	if getState(data.IndexLP) == LPSTOPPED {
+		return // terminated by the evaluation just completed
+	}
 
-		manageMessage(data, m)
+	m := waitMessage(data.IndexLP, data.done) // the process blocks until a message arrives
+	if m == nil {
+		return // cancelled
+	}
 
-		if State[data.IndexLP] != LPSTOPPED {
-			State[data.IndexLP] = LPRUNNING
-		}
+	manageMessage(data, m)
+
+	if getState(data.IndexLP) != LPSTOPPED {
+		setState(data.IndexLP, LPRUNNING)
 	}
 }
 
@@ -268,24 +423,32 @@ func createAntiMessage(msg *Message) *Message {
 	var e Event
 	var m Message
 
-	e = *CreateEvent(-msg.Ev.Id, msg.Ev.Time, Info{0, 0, ANTIMSG})
+	e = *CreateEvent(-msg.Ev.Id, msg.Ev.Time, Info{From: msg.Ev.Type.From, To: msg.Ev.Type.To, Flag: ANTIMSG}) // the receiver entity is kept for the forwarding
 	m = *CreateMessage(msg.Sender, msg.Receiver, e)
 
 	return &m
 }
 
 func annihilate(antimsg *Event, data *LocalData) {
-	done := false
-	done = IsPresent(antimsg.Time, data.ProcessedEvents)
-
-	if done && antimsg.Time <= data.SimTime {
-		rollback(antimsg.Time, data)
+	/* an executed event is undone together with the ones that are not before it */
+	for el := data.ProcessedEvents.Back(); el != nil; el = el.Prev() {
+		e := el.Value.(Event)
+		if e.Time < antimsg.Time {
+			break
+		}
+		if e.Time == antimsg.Time && e.Id == -antimsg.Id {
+			rollback(e.Time, e.Gen, data)
+			break
+		}
 	}
 
-	ev := CreateEvent(-antimsg.Id, 0, Info{0, 0, 0})
-	del := data.FutureEvents.DeleteExternId(ev)
-
-	if del.Id == ERR && del.Time == ERR {
+	/*
+	 * the event is identified by its identifier and its time: a rolled back
+	 * event can be sent again, with the same identifier, before the
+	 * anti-message of its previous version arrives
+	 */
+	ev := CreateEvent(-antimsg.Id, antimsg.Time, Info{})
+	if !data.FutureEvents.Delete(ev) {
 		antimsg.Time = data.SimTime // timestamping the anti-message it will be possible to rollback its reception
 
 		Insert(*antimsg, data.AntiMsg2Annihilate)
@@ -300,28 +463,29 @@ func checkAntimsg(ev *Event, data *LocalData) bool {
 }
 
 func ask4NewGvt(data *LocalData) {
-	if State[data.IndexLP] == LPSTOPPED {
+	if getState(data.IndexLP) == LPSTOPPED {
 		return
 	}
 	if CheckEvaluation() {
 		return
 	}
-	StartEvaluation(Lpnum)
+	round := StartEvaluation(Lpnum)
+	if round < 0 {
+		return // another LP has just started an evaluation
+	}
 
-	ev := CreateEvent(0, GVTEVAL, Info{0, 0, 0})
+	ev := CreateEvent(int64(round), GVTEVAL, Info{})
 	for i := 0; i < Lpnum; i++ {
-		if State[i] != LPSTOPPED && data.IndexLP != Pid(i) {
+		if getState(Pid(i)) != LPSTOPPED && data.IndexLP != Pid(i) {
 			msg := CreateMessage(data.IndexLP, Pid(i), *ev)
 			Send(msg)
 		}
 	}
-	evaluateLocalMin(data)
+	evaluateLocalMin(data, round)
 }
 
-func evaluateLocalMin(data *LocalData) {
-	var mintime Time = 1000000
-
-	State[data.IndexLP] = LPEVALGVT
+func evaluateLocalMin(data *LocalData, round int32) {
+	var mintime Time = MAXTIME
 
 	/* mintime computation and communication */
 	minheap := data.FutureEvents.GetMinTime()
@@ -338,15 +502,44 @@ func evaluateLocalMin(data *LocalData) {
 		mintime = minack
 	}
 
-	SetLocalMin(mintime, data.IndexLP)
+	if data.executing && data.SimTime < mintime {
+		mintime = data.SimTime
+	}
+	idle := !data.executing && (minheap == NOTIME || minheap >= Horizon)
+
+	ok, done := SetLocalMin(mintime, data.IndexLP, round, idle)
+	if !ok {
+		return // a GVTEVAL message of an old round
+	}
+	setState(data.IndexLP, LPEVALGVT)
 	data.GvtFlag = true // local min has been set
+	data.changed = false
 
 	data.Acked.Init()
+
+	if done {
+		gvtCompleted(data)
+	}
+}
+
+/*
+ * called by the LP that has completed an evaluation: if the GVT has reached
+ * the Horizon all the LPs are stopped, otherwise a new evaluation is
+ * started if needed to detect the termination
+ */
+func gvtCompleted(data *LocalData) {
+	t, again := gvtResult()
+	if t >= Horizon {
+		killall(data)
+		setState(data.IndexLP, LPSTOPPED)
+	} else if again {
+		ask4NewGvt(data)
+	}
 }
 
 func setGvt(gvt Time, data *LocalData) {
 
-	if State[data.IndexLP] == LPSTOPPED {
+	if getState(data.IndexLP) == LPSTOPPED {
 		return
 	}
 
@@ -357,24 +550,57 @@ func setGvt(gvt Time, data *LocalData) {
 	data.GvtFlag = false
 	data.Gvt = gvt
 
-	fossilCollection(gvt, data)
+	fossilCollection(committed(gvt), data)
+}
+
+/*
+ * the events up to the returned time can no longer be rolled back: with
+ * zero-delay events, an event with the time of the GVT can still be
+ * undone by one with a lower Gen
+ */
+func committed(gvt Time) Time {
+	if Lookahead == 0 {
+		return gvt - 1
+	}
+	return gvt
 }
 
 func fossilCollection(t Time, data *LocalData) {
+	commitEvents(t, data)
 	DeleteBefore(t, data.ProcessedEvents)
 	DeleteBefore(t, data.MsgSent)
-	State[data.IndexLP] = LPRUNNING
+	DeleteBefore(t, data.StateLog)
+	DeleteBefore(t, data.DrawLog)
+	fossilRetractions(t, data.Retractions)
+	setState(data.IndexLP, LPRUNNING)
 
 	data.Acked.Init()
 }
 
+/* the processed events with time <= t can no longer be rolled back */
+func commitEvents(t Time, data *LocalData) {
+	for el := data.ProcessedEvents.Front(); el != nil; el = el.Next() {
+		ev := el.Value.(Event)
+		if ev.Time > t {
+			break
+		}
+		data.Stats.Committed++
+		loadCommitted(&ev)
+		commitEntity(&ev)
+		if Tracer != nil {
+			Tracer.Write(&TraceRecord{data.IndexLP, ev})
+		}
+	}
+	atomic.StoreInt32(&lpCommitted[data.IndexLP], int32(t))
+}
+
 func sendAck(msg *Message, data *LocalData) {
 	var e *Event
 
 	if data.GvtFlag {
-		e = CreateEvent(msg.Ev.Id, ACK, Info{0, 0, YOURS})
+		e = CreateEvent(msg.Ev.Id, ACK, Info{Flag: YOURS})
 	} else {
-		e = CreateEvent(msg.Ev.Id, ACK, Info{0, 0, MINE})
+		e = CreateEvent(msg.Ev.Id, ACK, Info{Flag: MINE})
 	}
 	ack := CreateMessage(msg.Receiver, msg.Sender, *e)
 	Send(ack)
@@ -408,20 +634,8 @@ Loop:
 
 }
 
-func checkAllIdle() bool {
-	var ret bool = true
-Loop:
-	for i := 0; i < Lpnum; i++ {
-		if State[i] != LPIDLE {
-			ret = false
-			break Loop
-		}
-	}
-	return ret
-}
-
 func killall(data *LocalData) {
-	ev := CreateEvent(0, ABORTMSG, Info{0, 0, 0})
+	ev := CreateEvent(0, ABORTMSG, Info{})
 
 	for i := 0; i < Lpnum; i++ {
 		m := CreateMessage(data.IndexLP, Pid(i), *ev)
@@ -0,0 +1,415 @@
+package warp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+ * a deterministic PHOLD-like model: the successor of an event depends
+ * only on the event itself, so every correct run commits the same events
+ * as the sequential kernel. The Ids are assigned by the kernel, so the
+ * chain of events is identified by the high-order 16 bits of Flag and the
+ * position in the chain by the low-order 16 bits.
+ */
+const testEntities = 64
+
+func testHash(ev *Event) uint32 {
+	h := uint32(ev.Type.Flag)*2654435761 ^ uint32(ev.Time)*40503
+	h ^= h >> 13
+	h *= 0x5bd1e995
+	return h ^ h>>15
+}
+
+func testLP(entity int) Pid {
+	return Pid(entity * Lpnum / testEntities)
+}
+
+/* the payload of the events of testModel, that depends on the event */
+func testPayload(ev *Event) []byte {
+	p := make([]byte, 1+int(ev.Time)%8)
+	for i := range p {
+		p[i] = byte(ev.Type.From + ev.Type.To*i + int(ev.Time))
+	}
+	return p
+}
+
+func testModel(ev *Event, l *LocalData) {
+	if !bytes.Equal(ev.Type.Data, testPayload(ev)) {
+		panic(fmt.Sprintf("LP %d: event %v with the payload of another event", l.IndexLP, *ev))
+	}
+	h := testHash(ev)
+	to := int(h % testEntities)
+	next := CreateEvent(0, ev.Time+1+Time(h>>8%10), Info{From: ev.Type.To, To: to, Flag: ev.Type.Flag + 1})
+	next.Type.Data = testPayload(next)
+	if err := NoticeEvent(next, testLP(to), l); err != nil {
+		panic(err)
+	}
+}
+
+func testInitial(chains int) []Event {
+	evs := make([]Event, chains)
+	for c := range evs {
+		evs[c] = *CreateEvent(0, Time(c%7), Info{From: c % testEntities, To: c % testEntities, Flag: int32(c+1) << 16})
+		evs[c].Type.Data = testPayload(&evs[c])
+	}
+	return evs
+}
+
+/* the initial events of every LP */
+func testInit(chains int) func(l *LocalData) error {
+	return func(l *LocalData) error {
+		for _, ev := range testInitial(chains) {
+			if testLP(ev.Type.To) == l.IndexLP {
+				l.NewEvent(&ev)
+			}
+		}
+		return nil
+	}
+}
+
+func runSequential(lpn int, end Time, chains int) []TraceRecord {
+	res, err := Run(Config{LPs: lpn, EndTime: end, Handler: testModel, Sequential: true}, testInit(chains))
+	if err != nil {
+		panic(err)
+	}
+	return res.Trace
+}
+
+func runParallel(t *testing.T, lpn int, end Time, chains int) []TraceRecord {
+	return traceRun(t, Config{LPs: lpn, EndTime: end, Handler: testModel}, testInit(chains))
+}
+
+/* enables the trace, the returned function disables it and returns the committed events */
+func startTrace(t *testing.T) func() []TraceRecord {
+	var buf bytes.Buffer
+
+	tw, _ := NewTraceWriter(&buf, TRACEBIN)
+	SetTrace(tw)
+	return func() []TraceRecord {
+		SetTrace(nil)
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		trace, err := ReadTrace(&buf, TRACEBIN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trace
+	}
+}
+
+/*
+ * runs the simulation and returns its trace. Without cfg.Context the run
+ * must end within 30 seconds, a run cancelled by cfg.Context is not an
+ * error
+ */
+func traceRun(t *testing.T, cfg Config, init func(l *LocalData) error) []TraceRecord {
+	stop := startTrace(t)
+	defer SetTrace(nil)
+
+	ctx := cfg.Context
+	if ctx == nil {
+		c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		cfg.Context = c
+	}
+	_, err := Run(cfg, init)
+	switch {
+	case err == nil || ctx != nil && err == ctx.Err():
+	case err == context.DeadlineExceeded:
+		t.Fatalf("%d LPs: the simulation did not terminate", cfg.LPs)
+	default:
+		t.Fatalf("%d LPs: %v", cfg.LPs, err)
+	}
+	return stop()
+}
+
+/* the parallel run must commit exactly the events of the sequential one */
+func checkTrace(t *testing.T, lpn int, seq, par []TraceRecord) {
+	SortTrace(seq, true)
+	SortTrace(par, true)
+	if i := DiffTrace(seq, par, true); i >= 0 {
+		t.Fatalf("%d LPs: %d sequential and %d parallel events, they differ at position %d",
+			lpn, len(seq), len(par), i)
+	}
+	checkIds(t, par)
+}
+
+/* the Ids assigned by the kernel are unique, also across the rollbacks */
+func checkIds(t *testing.T, trace []TraceRecord) {
+	ids := make(map[int64]bool, len(trace))
+	for _, r := range trace {
+		id := r.Ev.Id
+		if id <= 0 || ids[id] || int(IdLP(id)) >= Lpnum || IdSeq(id) == 0 {
+			t.Fatalf("event %+v: invalid or duplicated Id (LP %d, sequence number %d)", r.Ev, IdLP(id), IdSeq(id))
+		}
+		ids[id] = true
+	}
+}
+
+func TestParallelTrace(t *testing.T) {
+	for _, lpn := range []int{1, 2, 4} {
+		seq := runSequential(lpn, 300, 32)
+		par := runParallel(t, lpn, 300, 32)
+		checkTrace(t, lpn, seq, par)
+	}
+}
+
+/*
+ * the delays of the messages cause rollbacks and anti-messages, testModel
+ * checks the payload of every event it executes
+ */
+func TestPayload(t *testing.T) {
+	for i := 0; ; i++ {
+		seq := runSequential(4, 300, 32)
+		d := &testDelayer{links: make(map[[2]Pid]*testLink), seed: int64(i)}
+		sendHook = d.send
+		par := runParallel(t, 4, 300, 32)
+		d.stop()
+		checkTrace(t, 4, seq, par)
+
+		s := CollectStats()
+		if s.Rollbacks > 0 && s.AntiSent > 0 {
+			break
+		}
+		if i == 10 {
+			t.Fatal("no rollback has sent anti-messages")
+		}
+	}
+}
+
+/* the limited optimism and the GVT threshold change the rollbacks, not the committed events */
+func TestWindow(t *testing.T) {
+	for _, window := range []Time{1, TOOFAR} {
+		seq := runSequential(4, 300, 32)
+		par := traceRun(t, Config{LPs: 4, EndTime: 300, Handler: testModel, Window: window, GvtThreshold: 20}, testInit(32))
+		checkTrace(t, 4, seq, par)
+	}
+}
+
+/* the implementations of the future events give the same committed events */
+func TestQueue(t *testing.T) {
+	seq := runSequential(4, 300, 32)
+	for _, q := range []int{QUEUEHEAP, QUEUELIST} {
+		par := traceRun(t, Config{LPs: 4, EndTime: 300, Handler: testModel, Queue: q}, testInit(32))
+		checkTrace(t, 4, seq, par)
+	}
+}
+
+/* to be run with -race: many short simulations with several LPs */
+func TestStress(t *testing.T) {
+	n := 10
+	if testing.Short() {
+		n = 3
+	}
+	for i := 0; i < n; i++ {
+		lpn := 2 + i%7
+		seq := runSequential(lpn, 100, 48)
+		par := runParallel(t, lpn, 100, 48)
+		checkTrace(t, lpn, seq, par)
+
+		s := CollectStats()
+		if s.Committed != len(par) {
+			t.Fatalf("%d LPs: Stats.Committed = %d, %d events in the trace", lpn, s.Committed, len(par))
+		}
+	}
+}
+
+/*
+ * delays the messages: every link (pair of LPs) is served by a goroutine
+ * that keeps the FIFO order, the delay is a latency (a message is never
+ * delivered before the previous one on the same link). The control
+ * messages (acks and GVT requests) get the longest delays, so that the LPs
+ * often go idle while the messages to them, or the acks of their messages,
+ * are still in flight.
+ */
+type testDelayer struct {
+	mu    sync.Mutex
+	links map[[2]Pid]*testLink
+	wg    sync.WaitGroup
+	seed  int64
+}
+
+type testLink struct {
+	ch   chan testDelayed
+	rnd  *rand.Rand
+	last time.Time // delivery time of the last message
+}
+
+type testDelayed struct {
+	msg Message
+	due time.Time
+}
+
+func (d *testDelayer) send(msg *Message) {
+	key := [2]Pid{msg.Sender, msg.Receiver}
+
+	d.mu.Lock()
+	l := d.links[key]
+	if l == nil {
+		l = &testLink{ch: make(chan testDelayed, 4*MAXBUFFER),
+			rnd: rand.New(rand.NewSource(d.seed*1000 + int64(key[0])*31 + int64(key[1])))}
+		d.links[key] = l
+		d.wg.Add(1)
+		go d.link(l)
+	}
+	max := 50
+	if msg.Ev.Time < 0 {
+		max = 500 // ACK, GVTEVAL, ABORTMSG
+	}
+	due := time.Now()
+	if l.rnd.Intn(4) > 0 {
+		due = due.Add(time.Duration(l.rnd.Intn(max)) * time.Microsecond)
+	}
+	if due.Before(l.last) {
+		due = l.last
+	}
+	l.last = due
+	d.mu.Unlock()
+
+	l.ch <- testDelayed{*msg, due}
+}
+
+func (d *testDelayer) link(l *testLink) {
+	for m := range l.ch {
+		time.Sleep(time.Until(m.due))
+		deliver(&m.msg)
+	}
+	d.wg.Done()
+}
+
+func (d *testDelayer) stop() {
+	sendHook = nil
+	d.mu.Lock()
+	for _, l := range d.links {
+		close(l.ch)
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+func TestTerminationDelays(t *testing.T) {
+	n := 6
+	if testing.Short() {
+		n = 2
+	}
+	for i := 0; i < n; i++ {
+		lpn := 2 + i%4
+		seq := runSequential(lpn, 150, 24)
+
+		d := &testDelayer{links: make(map[[2]Pid]*testLink), seed: int64(i)}
+		sendHook = d.send
+		par := runParallel(t, lpn, 150, 24)
+		d.stop()
+
+		checkTrace(t, lpn, seq, par)
+	}
+}
+
+/* few events: the LPs are idle most of the time */
+func TestTerminationSparse(t *testing.T) {
+	for lpn := 2; lpn <= 6; lpn += 2 {
+		seq := runSequential(lpn, 500, 1)
+		par := runParallel(t, lpn, 500, 1)
+		checkTrace(t, lpn, seq, par)
+	}
+}
+
+/* a cancelled run commits exactly the events before the GVT */
+func TestCancel(t *testing.T) {
+	for _, lpn := range []int{1, 3} {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		par := traceRun(t, Config{LPs: lpn, EndTime: 1 << 30, Handler: testModel, Context: ctx}, testInit(32))
+		cancel()
+
+		s := CollectStats()
+		if !s.Cancelled {
+			t.Fatalf("%d LPs: the simulation has not been cancelled", lpn)
+		}
+		cut := GetGvt()
+		if cut <= 0 || cut == ERR {
+			t.Fatalf("%d LPs: GVT %d after the cancellation", lpn, cut)
+		}
+		if s.Committed != len(par) {
+			t.Fatalf("%d LPs: Stats.Committed = %d, %d events in the trace", lpn, s.Committed, len(par))
+		}
+
+		var before []TraceRecord
+		for _, r := range par {
+			if r.Ev.Time > cut {
+				t.Fatalf("%d LPs: committed event %v after the GVT %d", lpn, r, cut)
+			} else if r.Ev.Time < cut {
+				before = append(before, r)
+			}
+		}
+		checkTrace(t, lpn, runSequential(lpn, cut, 32), before)
+	}
+}
+
+/* an error of the model stops both kernels and is returned by Run */
+func TestFail(t *testing.T) {
+	fail := errors.New("bad event")
+	handler := func(ev *Event, l *LocalData) {
+		if ev.Time >= 100 && ev.Type.To == 5 {
+			l.Fail(fail)
+			return
+		}
+		testModel(ev, l)
+	}
+	for _, seq := range []bool{true, false} {
+		res, err := Run(Config{LPs: 3, EndTime: 1 << 30, Handler: handler, Sequential: seq}, testInit(16))
+		if !errors.Is(err, fail) {
+			t.Fatalf("sequential %v: Run returned %v", seq, err)
+		}
+		if res == nil || res.Stats.Committed == 0 {
+			t.Errorf("sequential %v: no partial result", seq)
+		}
+		if seq {
+			for _, r := range res.Trace {
+				if r.Ev.Time >= 100 && r.Ev.Type.To == 5 {
+					t.Errorf("the failed event %v has been committed", r.Ev)
+				}
+			}
+		}
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	bad := []Config{
+		{LPs: -1, EndTime: 10, Handler: testModel},
+		{LPs: 2, EndTime: 0, Handler: testModel},
+		{LPs: 2, EndTime: 10},
+		{LPs: 2, EndTime: 10, Handler: testModel, Queue: 7},
+	}
+	for _, cfg := range bad {
+		if _, err := Run(cfg, nil); err == nil {
+			t.Errorf("Run(%+v) did not fail", cfg)
+		}
+	}
+
+	fail := errors.New("no events")
+	_, err := Run(Config{LPs: 2, EndTime: 10, Handler: testModel}, func(l *LocalData) error {
+		if l.IndexLP == 1 {
+			return fail
+		}
+		return nil
+	})
+	if err == nil {
+		t.Errorf("the error of the initialization has been lost")
+	}
+
+	res, err := Run(Config{LPs: 3, EndTime: 200, Handler: testModel}, testInit(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.LPs) != 3 || res.Stats.LPs != 3 || res.Stats.Committed == 0 || res.Stats.Cancelled {
+		t.Errorf("unexpected result %+v", res.Stats)
+	}
+}
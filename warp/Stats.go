@@ -0,0 +1,170 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * SIMULATION STATISTICS
+ *
+ * every LP updates its own counters in LocalData.Stats, the kernel
+ * aggregates them (and the GVT statistics) in a Stats value when the
+ * simulation is over.
+ */
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+/* counters of a single LP */
+type LPStats struct {
+	LP           Pid     `json:"lp"`
+	Processed    int     `json:"processed"`   // executed events, including the rolled back ones
+	Committed    int     `json:"committed"`   // events that can no longer be rolled back
+	RolledBack   int     `json:"rolled_back"` // executed events undone by a rollback
+	Rollbacks    int     `json:"rollbacks"`
+	AntiSent     int     `json:"anti_sent"`     // anti-messages sent to other LPs
+	AntiReceived int     `json:"anti_received"` // anti-messages received from other LPs
+	Sent         []int   `json:"sent"`          // Sent[j] = event messages sent to LP j
+	Efficiency   float64 `json:"efficiency"`    // committed / processed
+}
+
+type Stats struct {
+	LPs          int           `json:"lps"`
+	EndTime      Time          `json:"end_time"`
+	WallClock    time.Duration `json:"wall_clock_ns"`
+	Cancelled    bool          `json:"cancelled"` // only the events up to the GVT have been committed
+	Processed    int           `json:"processed"`
+	Committed    int           `json:"committed"`
+	RolledBack   int           `json:"rolled_back"`
+	Rollbacks    int           `json:"rollbacks"`
+	AntiSent     int           `json:"anti_sent"`
+	AntiReceived int           `json:"anti_received"`
+	GvtRounds    int           `json:"gvt_rounds"`
+	Migrations   int           `json:"migrations"`   // entities moved to another LP
+	GvtTotal     time.Duration `json:"gvt_total_ns"` // time spent in GVT evaluations
+	GvtMax       time.Duration `json:"gvt_max_ns"`   // the longest GVT evaluation
+	Efficiency   float64       `json:"efficiency"`   // committed / processed
+	EventRate    float64       `json:"event_rate"`   // committed events per wall clock second
+	PerLP        []LPStats     `json:"per_lp"`
+}
+
+func initLPStats(s *LPStats, i Pid) {
+	*s = LPStats{LP: i, Sent: make([]int, Lpnum)}
+}
+
+/*
+ * aggregates the statistics of all the initialized LPs, it must be called
+ * when the simulation is over
+ */
+func CollectStats() *Stats {
+	s := new(Stats)
+
+	s.LPs = Lpnum
+	s.EndTime = EndTime
+	s.WallClock = time.Since(StartTime)
+	s.Cancelled = Cancelled()
+	s.GvtRounds, s.GvtTotal, s.GvtMax = gvtStats()
+	s.Migrations = nMigrations
+
+	for _, data := range lpData {
+		if data == nil {
+			continue
+		}
+		lp := data.Stats
+		lp.Sent = append([]int(nil), lp.Sent...)
+		lp.Efficiency = efficiency(lp.Committed, lp.Processed)
+
+		s.Processed += lp.Processed
+		s.Committed += lp.Committed
+		s.RolledBack += lp.RolledBack
+		s.Rollbacks += lp.Rollbacks
+		s.AntiSent += lp.AntiSent
+		s.AntiReceived += lp.AntiReceived
+		s.PerLP = append(s.PerLP, lp)
+	}
+	s.Efficiency = efficiency(s.Committed, s.Processed)
+	if s.WallClock > 0 {
+		s.EventRate = float64(s.Committed) / s.WallClock.Seconds()
+	}
+	return s
+}
+
+func efficiency(committed, processed int) float64 {
+	if processed == 0 {
+		return 0
+	}
+	return float64(committed) / float64(processed)
+}
+
+func (s *Stats) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+/*
+ * one row per LP plus a final "total" row, the sent_N columns contain the
+ * number of event messages sent to LP N. Returns the first write error
+ */
+func (s *Stats) WriteCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+
+	head := []string{"lp", "processed", "committed", "rolled_back", "rollbacks",
+		"anti_sent", "anti_received", "efficiency"}
+	for j := 0; j < s.LPs; j++ {
+		head = append(head, "sent_"+strconv.Itoa(j))
+	}
+	if err := c.Write(head); err != nil {
+		return err
+	}
+
+	total := LPStats{Processed: s.Processed, Committed: s.Committed, RolledBack: s.RolledBack,
+		Rollbacks: s.Rollbacks, AntiSent: s.AntiSent, AntiReceived: s.AntiReceived,
+		Sent: make([]int, s.LPs), Efficiency: s.Efficiency}
+
+	for i := range s.PerLP {
+		lp := &s.PerLP[i]
+		if err := c.Write(statsRow(strconv.Itoa(int(lp.LP)), lp)); err != nil {
+			return err
+		}
+		for j := range lp.Sent {
+			total.Sent[j] += lp.Sent[j]
+		}
+	}
+	if err := c.Write(statsRow("total", &total)); err != nil {
+		return err
+	}
+
+	c.Flush()
+	return c.Error()
+}
+
+func statsRow(name string, lp *LPStats) []string {
+	row := []string{name,
+		strconv.Itoa(lp.Processed),
+		strconv.Itoa(lp.Committed),
+		strconv.Itoa(lp.RolledBack),
+		strconv.Itoa(lp.Rollbacks),
+		strconv.Itoa(lp.AntiSent),
+		strconv.Itoa(lp.AntiReceived),
+		strconv.FormatFloat(lp.Efficiency, 'f', 4, 64),
+	}
+	for _, n := range lp.Sent {
+		row = append(row, strconv.Itoa(n))
+	}
+	return row
+}
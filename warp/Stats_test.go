@@ -0,0 +1,100 @@
+package warp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+/* the statistics written in JSON and in CSV are read back unchanged */
+func TestStatsOutput(t *testing.T) {
+	res, err := Run(Config{LPs: 3, EndTime: 200, Handler: testModel}, testInit(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := res.Stats
+	if s.Committed == 0 || len(s.PerLP) != 3 {
+		t.Fatalf("unexpected statistics %+v", s)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var js Stats
+	if err := json.Unmarshal(buf.Bytes(), &js); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&js, s) {
+		t.Fatalf("JSON: read %+v, want %+v", js, *s)
+	}
+
+	buf.Reset()
+	if err := s.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(s.PerLP)+2 || len(rows[0]) != 8+s.LPs || rows[0][8] != "sent_0" {
+		t.Fatalf("CSV: %d rows, header %v", len(rows), rows[0])
+	}
+	total := LPStats{Processed: s.Processed, Committed: s.Committed, RolledBack: s.RolledBack,
+		Rollbacks: s.Rollbacks, AntiSent: s.AntiSent, AntiReceived: s.AntiReceived,
+		Sent: make([]int, s.LPs), Efficiency: s.Efficiency}
+	for i, row := range rows[1:] {
+		want := &total
+		if i < len(s.PerLP) {
+			want = &s.PerLP[i]
+			for j, n := range want.Sent {
+				total.Sent[j] += n
+			}
+		} else if row[0] != "total" {
+			t.Fatalf("CSV: last row %v", row)
+		}
+		var lp LPStats // LP 0 in the total row
+		if want != &total {
+			n, err := strconv.Atoi(row[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			lp.LP = Pid(n)
+		}
+		fields := []*int{&lp.Processed, &lp.Committed, &lp.RolledBack, &lp.Rollbacks, &lp.AntiSent, &lp.AntiReceived}
+		for j, f := range fields {
+			if *f, err = strconv.Atoi(row[1+j]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if lp.Efficiency, err = strconv.ParseFloat(row[7], 64); err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range row[8:] {
+			n, err := strconv.Atoi(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lp.Sent = append(lp.Sent, n)
+		}
+		w := *want
+		w.Efficiency, _ = strconv.ParseFloat(strconv.FormatFloat(w.Efficiency, 'f', 4, 64), 64)
+		if !reflect.DeepEqual(lp, w) {
+			t.Fatalf("CSV: row %v, want %+v", row, w)
+		}
+	}
+
+	if s.WriteJSON(failWriter{}) == nil || s.WriteCSV(failWriter{}) == nil {
+		t.Fatal("write error not returned")
+	}
+}
@@ -0,0 +1,334 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+package warp
+
+/*
+ * COMMITTED EVENT TRACE
+ *
+ * the parallel kernel writes an event in the trace when it is fossil
+ * collected (i.e. it can no longer be rolled back), the sequential kernel
+ * as soon as it is executed. Each record contains the LP that executed the
+ * event, its timestamp, identifier, sender and the From, To, Flag and Data
+ * of its Info. The Gen of the event is not traced, so ReadTrace returns the
+ * events without it, and an empty payload is read back as nil.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+/* trace formats */
+const (
+	TRACEBIN  = iota // little-endian records, each followed by the length and the bytes of its payload
+	TRACECSV  = iota // comma separated values, with header
+	TRACEJSON = iota // one JSON object per line
+)
+
+const traceMagic = "GOWARPT1"
+
+/* an entry of the committed event trace */
+type TraceRecord struct {
+	LP Pid // the LP that executed the event
+	Ev Event
+}
+
+type traceBin struct {
+	LP, Sender int32
+	Time       int32
+	Id         int64
+	From, To   int64
+	Flag       int32
+}
+
+type traceJSON struct {
+	LP     Pid    `json:"lp"`
+	Time   Time   `json:"time"`
+	Id     int64  `json:"id"`
+	Sender Pid    `json:"sender"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Flag   int32  `json:"flag"`
+	Data   []byte `json:"data,omitempty"` // base64
+}
+
+var traceHeader = []string{"lp", "time", "id", "sender", "from", "to", "flag", "data"} // data in hex
+
+/* writes trace records, it can be shared by all the LPs */
+type TraceWriter struct {
+	mu     sync.Mutex
+	format int
+	w      *bufio.Writer
+	c      io.Closer
+	csv    *csv.Writer
+	json   *json.Encoder
+	err    error
+}
+
+var Tracer *TraceWriter // if not nil, the committed events are written here
+
+/* sets the trace writer used by the kernels, nil disables the trace */
+func SetTrace(t *TraceWriter) {
+	Tracer = t
+}
+
+func NewTraceWriter(w io.Writer, format int) (*TraceWriter, error) {
+	t := &TraceWriter{format: format, w: bufio.NewWriter(w)}
+	if c, ok := w.(io.Closer); ok {
+		t.c = c
+	}
+
+	switch format {
+	case TRACEBIN:
+		_, t.err = t.w.WriteString(traceMagic)
+	case TRACECSV:
+		t.csv = csv.NewWriter(t.w)
+		t.err = t.csv.Write(traceHeader)
+	case TRACEJSON:
+		t.json = json.NewEncoder(t.w)
+	default:
+		return nil, fmt.Errorf("GO-WARP: unknown trace format %d", format)
+	}
+	return t, t.err
+}
+
+/* the trace format is chosen by the file extension: .csv, .json/.jsonl or binary */
+func TraceFormat(path string) int {
+	switch filepath.Ext(path) {
+	case ".csv":
+		return TRACECSV
+	case ".json", ".jsonl":
+		return TRACEJSON
+	}
+	return TRACEBIN
+}
+
+func CreateTraceFile(path string) (*TraceWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := NewTraceWriter(f, TraceFormat(path))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+/* writes a record, the first error is kept and returned by Close */
+func (t *TraceWriter) Write(r *TraceRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err != nil {
+		return
+	}
+	ev := &r.Ev
+	switch t.format {
+	case TRACEBIN:
+		b := traceBin{int32(r.LP), int32(ev.Sender), int32(ev.Time), ev.Id,
+			int64(ev.Type.From), int64(ev.Type.To), ev.Type.Flag}
+		if t.err = binary.Write(t.w, binary.LittleEndian, &b); t.err == nil {
+			t.err = binary.Write(t.w, binary.LittleEndian, uint32(len(ev.Type.Data)))
+		}
+		if t.err == nil {
+			_, t.err = t.w.Write(ev.Type.Data)
+		}
+	case TRACECSV:
+		t.err = t.csv.Write([]string{
+			strconv.Itoa(int(r.LP)),
+			strconv.Itoa(int(ev.Time)),
+			strconv.FormatInt(ev.Id, 10),
+			strconv.Itoa(int(ev.Sender)),
+			strconv.Itoa(ev.Type.From),
+			strconv.Itoa(ev.Type.To),
+			strconv.Itoa(int(ev.Type.Flag)),
+			hex.EncodeToString(ev.Type.Data),
+		})
+	case TRACEJSON:
+		t.err = t.json.Encode(traceJSON{r.LP, ev.Time, ev.Id, ev.Sender,
+			ev.Type.From, ev.Type.To, ev.Type.Flag, ev.Type.Data})
+	}
+}
+
+/* flushes the buffered records and closes the underlying writer */
+func (t *TraceWriter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.csv != nil {
+		t.csv.Flush()
+		if t.err == nil {
+			t.err = t.csv.Error()
+		}
+	}
+	if err := t.w.Flush(); t.err == nil {
+		t.err = err
+	}
+	if t.c != nil {
+		if err := t.c.Close(); t.err == nil {
+			t.err = err
+		}
+	}
+	return t.err
+}
+
+func ReadTrace(r io.Reader, format int) ([]TraceRecord, error) {
+	var trace []TraceRecord
+
+	rd := bufio.NewReader(r)
+	switch format {
+	case TRACEBIN:
+		magic := make([]byte, len(traceMagic))
+		if _, err := io.ReadFull(rd, magic); err != nil || string(magic) != traceMagic {
+			return nil, errors.New("GO-WARP: not a binary trace")
+		}
+		for {
+			var b traceBin
+			err := binary.Read(rd, binary.LittleEndian, &b)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return trace, err
+			}
+			var n uint32
+			if err := binary.Read(rd, binary.LittleEndian, &n); err != nil {
+				return trace, fmt.Errorf("GO-WARP: trace record %d: %v", len(trace)+1, err)
+			}
+			ev := Event{Id: b.Id, Time: Time(b.Time), Type: Info{From: int(b.From), To: int(b.To), Flag: b.Flag}, Sender: Pid(b.Sender)}
+			if n > 0 {
+				ev.Type.Data = make([]byte, n)
+				if _, err := io.ReadFull(rd, ev.Type.Data); err != nil {
+					return trace, fmt.Errorf("GO-WARP: trace record %d: %v", len(trace)+1, err)
+				}
+			}
+			trace = append(trace, TraceRecord{Pid(b.LP), ev})
+		}
+	case TRACECSV:
+		c := csv.NewReader(rd)
+		c.FieldsPerRecord = len(traceHeader)
+		for line := 1; ; line++ {
+			fields, err := c.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return trace, err
+			}
+			if line == 1 {
+				continue // header
+			}
+			var v [7]int
+			for i := range v {
+				if v[i], err = strconv.Atoi(fields[i]); err != nil {
+					return trace, fmt.Errorf("GO-WARP: trace line %d, field %s: %v", line, traceHeader[i], err)
+				}
+			}
+			ev := Event{Id: int64(v[2]), Time: Time(v[1]), Type: Info{From: v[4], To: v[5], Flag: int32(v[6])}, Sender: Pid(v[3])}
+			if fields[7] != "" {
+				if ev.Type.Data, err = hex.DecodeString(fields[7]); err != nil {
+					return trace, fmt.Errorf("GO-WARP: trace line %d, field data: %v", line, err)
+				}
+			}
+			trace = append(trace, TraceRecord{Pid(v[0]), ev})
+		}
+	case TRACEJSON:
+		dec := json.NewDecoder(rd)
+		for {
+			var j traceJSON
+			err := dec.Decode(&j)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return trace, err
+			}
+			ev := Event{Id: j.Id, Time: j.Time, Type: Info{From: j.From, To: j.To, Flag: j.Flag, Data: j.Data}, Sender: j.Sender}
+			trace = append(trace, TraceRecord{j.LP, ev})
+		}
+	default:
+		return nil, fmt.Errorf("GO-WARP: unknown trace format %d", format)
+	}
+	return trace, nil
+}
+
+func ReadTraceFile(path string) ([]TraceRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadTrace(f, TraceFormat(path))
+}
+
+/* compares two records in the canonical order: time, LP, sender, id, From, To, Flag, Data */
+func traceLess(a, b *TraceRecord, ignoreId bool) bool {
+	switch {
+	case a.Ev.Time != b.Ev.Time:
+		return a.Ev.Time < b.Ev.Time
+	case a.LP != b.LP:
+		return a.LP < b.LP
+	case a.Ev.Sender != b.Ev.Sender:
+		return a.Ev.Sender < b.Ev.Sender
+	case !ignoreId && a.Ev.Id != b.Ev.Id:
+		return a.Ev.Id < b.Ev.Id
+	case a.Ev.Type.From != b.Ev.Type.From:
+		return a.Ev.Type.From < b.Ev.Type.From
+	case a.Ev.Type.To != b.Ev.Type.To:
+		return a.Ev.Type.To < b.Ev.Type.To
+	case a.Ev.Type.Flag != b.Ev.Type.Flag:
+		return a.Ev.Type.Flag < b.Ev.Type.Flag
+	}
+	return bytes.Compare(a.Ev.Type.Data, b.Ev.Type.Data) < 0
+}
+
+/*
+ * sorts a trace in the canonical order, if ignoreId is true the event
+ * identifiers are not considered (e.g. when the model assigns them
+ * depending on the scheduling of the LPs)
+ */
+func SortTrace(trace []TraceRecord, ignoreId bool) {
+	sort.SliceStable(trace, func(i, j int) bool {
+		return traceLess(&trace[i], &trace[j], ignoreId)
+	})
+}
+
+/*
+ * returns the position of the first divergence between two sorted traces,
+ * or -1 if they are equal
+ */
+func DiffTrace(a, b []TraceRecord, ignoreId bool) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if traceLess(&a[i], &b[i], ignoreId) || traceLess(&b[i], &a[i], ignoreId) {
+			return i
+		}
+	}
+	if len(a) < len(b) {
+		return len(a)
+	} else if len(b) < len(a) {
+		return len(b)
+	}
+	return -1
+}
@@ -0,0 +1,43 @@
+package warp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+/* the records written in every format are read back with their payload, without the Gen */
+func TestTraceFormats(t *testing.T) {
+	trace := []TraceRecord{
+		{0, Event{Id: 1, Time: 0, Type: Info{From: 3, To: 4, Flag: 5}, Sender: 0}},
+		{2, Event{Id: 3<<IDSEQBITS | 12345, Time: 17, Type: Info{From: -1, To: 1 << 40, Flag: -7, Data: []byte("payload")}, Sender: 1, Gen: 2}},
+		{1, Event{Id: 7, Time: MAXTIME - 1, Type: Info{Flag: 1 << 30}, Sender: 2}},
+		{3, Event{Id: 8, Time: 20, Type: Info{To: 2, Data: []byte{0, 0xff, ',', '\n', '"'}}, Sender: 3}},
+	}
+	want := make([]TraceRecord, len(trace))
+	for i, r := range trace {
+		r.Ev.Gen = 0
+		want[i] = r
+	}
+
+	for _, format := range []int{TRACEBIN, TRACECSV, TRACEJSON} {
+		var buf bytes.Buffer
+		tw, err := NewTraceWriter(&buf, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range trace {
+			tw.Write(&trace[i])
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadTrace(&buf, format)
+		if err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("format %d: read %+v, want %+v", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+	GO-WARP: a Time Warp simulator written in Go
+	http://pads.cs.unibo.it
+
+	This file is part of GO-WARP.  GO-WARP is free software, you can
+	redistribute it and/or modify it under the terms of the Revised BSD License.
+
+	For more information please see the LICENSE file.
+
+	Copyright 2014, Gabriele D'Angelo, Moreno Marzolla, Pietro Ansaloni
+	Computer Science Department, University of Bologna, Italy
+*/
+
+/*
+ * helpers for the tests of the models: the kernel tests have their own
+ * in package warp (see traceRun and startTrace in Sim_test.go), that
+ * cannot be imported by other packages
+ */
+package warptest
+
+import (
+	"fmt"
+	"github.com/jeffallen/go-warp/warp"
+	"reflect"
+	"testing"
+)
+
+/*
+ * runs the model on the sequential kernel and then on the parallel one
+ * once for each partition in parts, the nil Partitioner is BlockPartition:
+ * after every parallel run state must return what it returned after the
+ * sequential one. run executes the model with the given configuration,
+ * state returns a copy of the final state of the model. cfg.Window is
+ * only used by the parallel runs
+ */
+func Compare(t testing.TB, cfg warp.Config, parts []warp.Partitioner, run func(cfg warp.Config) (*warp.Result, error), state func() interface{}) {
+	t.Helper()
+
+	seq := cfg
+	seq.Sequential = true
+	if _, err := run(seq); err != nil {
+		t.Fatal(err)
+	}
+	want := state()
+
+	for _, part := range parts {
+		par := cfg
+		par.Partition = part
+		res, err := run(par)
+		if err != nil {
+			t.Fatalf("%T: %v", part, err)
+		}
+		if got := state(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%T: %s", part, diff(reflect.ValueOf(got), reflect.ValueOf(want), "state"))
+		}
+		t.Logf("%T: %d events, %d rollbacks", part, res.Stats.Committed, res.Stats.Rollbacks)
+	}
+}
+
+/* describes the first difference between got and want, path is the name of got */
+func diff(got, want reflect.Value, path string) string {
+	switch {
+	case got.Kind() == reflect.Slice && got.Len() == want.Len():
+		for i := 0; i < got.Len(); i++ {
+			if !reflect.DeepEqual(got.Index(i).Interface(), want.Index(i).Interface()) {
+				return diff(got.Index(i), want.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case got.Kind() == reflect.Struct:
+		for i := 0; i < got.NumField(); i++ {
+			if !got.Field(i).CanInterface() {
+				break
+			}
+			if !reflect.DeepEqual(got.Field(i).Interface(), want.Field(i).Interface()) {
+				return diff(got.Field(i), want.Field(i), path+"."+got.Type().Field(i).Name)
+			}
+		}
+	}
+	return fmt.Sprintf("%s is %+v, want %+v", path, got.Interface(), want.Interface())
+}